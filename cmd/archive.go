@@ -0,0 +1,304 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// archiveRetentionSetting holds how long completed tasks stay in the list
+// before they are archived automatically, archiveLastRunSetting the day they
+// last were.
+const (
+	archiveRetentionSetting = "archive.retention"
+	archiveLastRunSetting   = "archive.last_run"
+)
+
+// archiveInterval is how often serve runs the automatic archiving.
+const archiveInterval = time.Hour
+
+// archiveCmd represents the archive command
+var archiveCmd = &cobra.Command{
+	Use:   "archive [id...]",
+	Short: "Archive tasks",
+	Long: `Archive tasks by ID or UID prefix, or every task matching --status and --older-than.
+Archived tasks are hidden from list unless --archived is given, and from export
+unless --include-archived is given.`,
+	Annotations: writeCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, _ := cmd.Flags().GetString("status")
+		olderThan, _ := cmd.Flags().GetString("older-than")
+
+		if len(args) == 0 && status == "" && olderThan == "" {
+			fmt.Printf("%s Provide task IDs or a --status / --older-than filter\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		var age time.Duration
+		if olderThan != "" {
+			var err error
+			if age, err = utils.ParseDuration(olderThan); err != nil {
+				fmt.Printf("%s %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+		}
+
+		store := promptStore("Where would you like to archive from?")
+		tasks, err := store.List()
+		if err != nil {
+			fmt.Printf("%s Failed to fetch tasks: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		var selected []models.Task
+		if len(args) > 0 {
+			for _, ref := range args {
+				task, err := matchTask(tasks, ref)
+				if err != nil {
+					fmt.Printf("%s %v\n", promptui.IconBad, err)
+					os.Exit(1)
+				}
+				selected = append(selected, task)
+			}
+		} else {
+			selected = tasksToArchive(tasks, status, age)
+		}
+
+		for _, task := range selected {
+			if err := archiveTask(store, task); err != nil {
+				fmt.Printf("%s Failed to archive task %d: %v\n", promptui.IconBad, task.ID, err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("%s Archived %d task(s)\n", promptui.IconGood, len(selected))
+	},
+}
+
+var archiveRestoreCmd = &cobra.Command{
+	Use:         "restore <id>",
+	Short:       "Move an archived task back to the list",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writeCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := promptStore("Where would you like to restore from?")
+
+		tasks, err := store.Archived()
+		if err != nil {
+			fmt.Printf("%s Failed to fetch the archive: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		task, err := matchTask(tasks, args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		task.ArchivedAt = nil
+		if err := store.Update(task); err != nil {
+			fmt.Printf("%s Failed to restore task %d: %v\n", promptui.IconBad, task.ID, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Task with ID %d restored from the archive\n", promptui.IconGood, task.ID)
+	},
+}
+
+var archiveRetentionCmd = &cobra.Command{
+	Use:   "retention [duration|off]",
+	Short: "Show or set automatic archiving of completed tasks",
+	Long: `Completed tasks are archived automatically once they were completed for longer
+than the retention, e.g. 30d, once a day by the first command changing tasks,
+or by serve. Tasks of an encrypted store wait for a command unlocking it. Use
+"off" to disable it.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			retention, err := database.GetSetting(archiveRetentionSetting)
+			if err != nil {
+				fmt.Printf("%s Failed to read the retention: %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+			if retention == "" {
+				fmt.Println("Automatic archiving is off.")
+				return
+			}
+			fmt.Printf("Completed tasks are archived after %s.\n", retention)
+			return
+		}
+
+		retention := args[0]
+		if retention == "off" {
+			retention = ""
+		} else if _, err := utils.ParseDuration(retention); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		if err := database.SetSetting(archiveRetentionSetting, retention); err != nil {
+			fmt.Printf("%s Failed to save the retention: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		// The new retention applies from the next command on.
+		database.SetSetting(archiveLastRunSetting, "")
+		fmt.Printf("%s Retention updated\n", promptui.IconGood)
+	},
+}
+
+func init() {
+	archiveCmd.Flags().String("status", "", "Archive every task with this status")
+	archiveCmd.Flags().String("older-than", "", "Only archive tasks not updated for this long, e.g. 30d")
+
+	archiveCmd.AddCommand(archiveRestoreCmd)
+	archiveCmd.AddCommand(archiveRetentionCmd)
+	rootCmd.AddCommand(archiveCmd)
+}
+
+// archiveTask marks the task as archived.
+func archiveTask(store taskStore, task models.Task) error {
+	now := time.Now().UTC()
+	task.ArchivedAt = &now
+	return store.Update(task)
+}
+
+// tasksToArchive selects the tasks with the status, when given, that were not
+// updated for at least age.
+func tasksToArchive(tasks []models.Task, status string, age time.Duration) []models.Task {
+	var selected []models.Task
+	for _, task := range tasks {
+		if status != "" && task.Status != status {
+			continue
+		}
+		if time.Since(task.UpdatedAt) < age {
+			continue
+		}
+		selected = append(selected, task)
+	}
+	return selected
+}
+
+// writeCommand annotates the commands changing tasks, the root command runs
+// autoArchive before them. Read-only commands leave the tasks as they are.
+var writeCommand = map[string]string{"writes": "tasks"}
+
+// autoArchive archives the completed tasks of the local backends in use that
+// were completed longer than the configured retention ago, once a day. The
+// passphrase of an encrypted store is not asked for, the store is archived
+// by the first run of the day it is unlocked in. These changes are not
+// journaled: undoing them would only see them archived again on the next run.
+func autoArchive() {
+	retention, err := database.GetSetting(archiveRetentionSetting)
+	if err != nil || retention == "" {
+		return
+	}
+	age, err := utils.ParseDuration(retention)
+	if err != nil {
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	if lastRun, err := database.GetSetting(archiveLastRunSetting); err != nil || lastRun == today {
+		return
+	}
+
+	key, err := tasks.DatabaseKey(database.GetDB(), keyring.Unlocked)
+	if err != nil {
+		return
+	}
+
+	journalPaused = true
+	defer func() { journalPaused = false }()
+
+	locked := false
+	for _, store := range autoArchiveStores() {
+		list, err := store.List()
+		if errors.Is(err, encryption.ErrLocked) {
+			locked = true
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%s Automatic archiving of %s failed: %v\n", promptui.IconWarn, store.Name(), err)
+			continue
+		}
+		completedAt, err := completionTimes(store.Key(), key)
+		if err != nil {
+			fmt.Printf("%s Automatic archiving of %s failed: %v\n", promptui.IconWarn, store.Name(), err)
+			continue
+		}
+
+		for _, task := range list {
+			if task.Status != "completed" {
+				continue
+			}
+			// Tasks completed before the history was kept are dated by their
+			// last update.
+			completed, ok := completedAt[task.UID]
+			if !ok {
+				completed = task.UpdatedAt
+			}
+			if time.Since(completed) < age {
+				continue
+			}
+			if err := archiveTask(store, task); err != nil {
+				fmt.Printf("%s Automatic archiving of task %d failed: %v\n", promptui.IconWarn, task.ID, err)
+			}
+		}
+	}
+
+	if !locked {
+		database.SetSetting(archiveLastRunSetting, today)
+	}
+}
+
+// autoArchiveStores returns the local store of the backend setting, both
+// when it is unset. They never ask for a passphrase.
+func autoArchiveStores() []taskStore {
+	sqlite := &tasks.SQLiteStore{DB: database.GetDB(), OnChange: recordChange, Keys: keyring.Unlocked}
+	csvStore := &tasks.CSVStore{Path: getCSVFilePath(), OnChange: recordChange, Keys: keyring.Unlocked}
+	switch cfg.Backend {
+	case "sqlite":
+		return []taskStore{sqlite}
+	case "csv":
+		return []taskStore{csvStore}
+	case "remote":
+		return nil
+	}
+	return []taskStore{sqlite, csvStore}
+}
+
+// completionTimes returns when the tasks of the backend were last completed
+// by UID, following their history decrypted with key.
+func completionTimes(backend string, key *encryption.Key) (map[string]time.Time, error) {
+	rows, err := database.GetDB().Query(`SELECT task_uid, new_value, changed_at FROM task_history
+		WHERE backend = ? AND field = 'status' AND task_uid IS NOT NULL ORDER BY changed_at, id`, backend)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the task history: %v", err)
+	}
+	defer rows.Close()
+
+	completedAt := make(map[string]time.Time)
+	for rows.Next() {
+		var uid string
+		var status sql.NullString
+		var changedAt time.Time
+		if err := rows.Scan(&uid, &status, &changedAt); err != nil {
+			return nil, fmt.Errorf("error reading the task history: %v", err)
+		}
+		value, err := key.DecryptString(status.String, historyLocation("new_value", uid))
+		if err != nil {
+			return nil, err
+		}
+		if value == "completed" {
+			completedAt[uid] = changedAt
+		}
+	}
+	return completedAt, rows.Err()
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/config"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+)
+
+// addCompletedTask adds a task completed completedAgo ago and last updated
+// updatedAgo ago.
+func addCompletedTask(t *testing.T, title string, completedAgo time.Duration, updatedAgo time.Duration) {
+	t.Helper()
+	store := newSQLiteStore()
+	task, err := store.Add(models.Task{Title: title, Status: "pending", CreatedAt: time.Now().UTC(), UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	task.Status = "completed"
+	if err := store.Update(task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	now := time.Now().UTC()
+	db := database.GetDB()
+	if _, err := db.Exec(`UPDATE task_history SET changed_at = ? WHERE task_uid = ? AND field = 'status'`, now.Add(-completedAgo), task.UID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`UPDATE tasks SET updated_at = ? WHERE id = ?`, now.Add(-updatedAgo), task.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func archivedTitles(t *testing.T) []string {
+	t.Helper()
+	archived, err := newSQLiteStore().Archived()
+	if err != nil {
+		t.Fatalf("Archived: %v", err)
+	}
+	var titles []string
+	for _, task := range archived {
+		titles = append(titles, task.Title)
+	}
+	return titles
+}
+
+func TestAutoArchive(t *testing.T) {
+	useTestWorkspace(t)
+	const day = 24 * time.Hour
+	database.SetSetting(archiveRetentionSetting, "30d")
+
+	// Completed long ago, its description was edited since.
+	addCompletedTask(t, "Call Acme", 40*day, time.Hour)
+	// Completed yesterday, not updated for long before.
+	addCompletedTask(t, "Call Globex", day, 40*day)
+	if _, err := newSQLiteStore().Add(models.Task{Title: "Call Initech", Status: "pending", UpdatedAt: time.Now().Add(-40 * day)}); err != nil {
+		t.Fatal(err)
+	}
+
+	autoArchive()
+	if titles := archivedTitles(t); len(titles) != 1 || titles[0] != "Call Acme" {
+		t.Fatalf("archived %v, want the task completed 40 days ago only", titles)
+	}
+
+	// It runs once a day.
+	database.GetDB().Exec(`UPDATE task_history SET changed_at = ? WHERE field = 'status'`, time.Now().UTC().Add(-40*day))
+	autoArchive()
+	if titles := archivedTitles(t); len(titles) != 1 {
+		t.Fatalf("archived %v on the second run of the day", titles)
+	}
+	database.SetSetting(archiveLastRunSetting, time.Now().Add(-day).Format("2006-01-02"))
+	autoArchive()
+	if titles := archivedTitles(t); len(titles) != 2 {
+		t.Errorf("archived %v the next day, want Call Globex too", titles)
+	}
+}
+
+func TestAutoArchiveLocked(t *testing.T) {
+	useTestWorkspace(t)
+	database.SetSetting(archiveRetentionSetting, "30d")
+	addCompletedTask(t, "Call Acme", 40*24*time.Hour, 40*24*time.Hour)
+	encryptTestWorkspace(t, "correct horse")
+
+	// The passphrase is not asked for, the next unlocked run archives.
+	keyring = &encryption.Keyring{Passphrase: func() (string, error) {
+		t.Error("the passphrase was asked")
+		return "", encryption.ErrLocked
+	}}
+	autoArchive()
+	if lastRun, _ := database.GetSetting(archiveLastRunSetting); lastRun != "" {
+		t.Errorf("the locked run was recorded as done on %s", lastRun)
+	}
+
+	keyring = &encryption.Keyring{Passphrase: func() (string, error) { return "correct horse", nil }}
+	if _, err := contentKey(); err != nil {
+		t.Fatalf("unlocking: %v", err)
+	}
+	autoArchive()
+	if titles := archivedTitles(t); len(titles) != 1 {
+		t.Errorf("archived %v once unlocked, want Call Acme", titles)
+	}
+}
+
+func TestAutoArchiveWriteCommands(t *testing.T) {
+	tests := []struct {
+		cmd      *cobra.Command
+		archives bool
+	}{
+		{cmd: listCmd},
+		{cmd: showCmd},
+		{cmd: statsCmd},
+		{cmd: undoCmd},
+		{cmd: createCmd, archives: true},
+		{cmd: editCmd, archives: true},
+		{cmd: trashPurgeCmd, archives: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd.Name(), func(t *testing.T) {
+			useTestWorkspace(t)
+			database.SetSetting(archiveRetentionSetting, "30d")
+			addCompletedTask(t, "Call Acme", 40*24*time.Hour, 40*24*time.Hour)
+			database.CloseDB()
+
+			dir := filepath.Dir(workspace.DB)
+			t.Setenv("TASKS_CLI_CONFIG", filepath.Join(dir, "config.yaml"))
+			t.Setenv("TASKS_CLI_WORKSPACE", config.DefaultWorkspace)
+			t.Setenv("TASKS_CLI_BACKEND", "sqlite")
+			t.Setenv("TASKS_CLI_DB", workspace.DB)
+			t.Setenv("TASKS_CLI_CSV", workspace.CSV)
+			t.Setenv("XDG_RUNTIME_DIR", dir)
+			t.Cleanup(func() { cfgFile = "" })
+
+			rootCmd.PersistentPreRun(tt.cmd, nil)
+			if archived := len(archivedTitles(t)) == 1; archived != tt.archives {
+				t.Errorf("%s archived: %v, want %v", tt.cmd.Name(), archived, tt.archives)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/backup"
+	"github.com/unf6/testing/pkg/database"
+)
+
+const (
+	// preRestoreLabel labels the backups restore makes of the current files.
+	preRestoreLabel = "pre-restore"
+	// preRestoreBackups is the number of pre-restore backups kept.
+	preRestoreBackups = 5
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up the database and CSV file of the workspace",
+	Long: `Back up the database and CSV file of the workspace into a timestamped
+tasks-<time>.tar.gz archive of the backup directory, with a .sha256 checksum
+file beside it. The database is copied consistently even while it is in use.
+Encrypted task content stays encrypted in the archive.
+
+Older backups are rotated: the newest backup of each of the last --keep-daily
+days and of the last --keep-weekly weeks is kept, the others are removed. Give
+--keep-daily 0 --keep-weekly 0 to keep every backup. Restore a backup with
+restore.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := backupDir(cmd)
+		daily, _ := cmd.Flags().GetInt("keep-daily")
+		weekly, _ := cmd.Flags().GetInt("keep-weekly")
+		if daily < 0 || weekly < 0 {
+			fmt.Printf("%s --keep-daily and --keep-weekly cannot be negative\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		created, err := backup.Create(dir, database.GetDB(), workspace.CSV, time.Now(), "")
+		if err != nil {
+			fmt.Printf("%s Failed to back up: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Backed up to %s (%s)\n", promptui.IconGood, created.Path, formatSize(created.Size))
+
+		if daily == 0 && weekly == 0 {
+			return
+		}
+		removed, err := backup.Rotate(dir, daily, weekly)
+		if err != nil {
+			fmt.Printf("%s Failed to rotate backups: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		for _, b := range removed {
+			fmt.Printf("  Removed %s\n", b.Name())
+		}
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:               "list",
+	Short:             "List the backups of the workspace, newest first",
+	Args:              cobra.NoArgs,
+	PersistentPreRun:  func(cmd *cobra.Command, args []string) { loadConfig() },
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := backupDir(cmd)
+		backups, err := backup.List(dir)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups in %s\n", dir)
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tSIZE")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", b.Name(), formatDate(b.CreatedAt), formatSize(b.Size))
+		}
+		w.Flush()
+	},
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Restore the database and CSV file of the workspace from a backup",
+	Long: `Restore the database and CSV file of the workspace from a backup, given as a
+path or as a name of the backup directory. The backup is verified against its
+checksums and the integrity of its database first, and must have been made
+from the database of the workspace unless --force is given. The current files
+are backed up to a tasks-<time>-pre-restore.tar.gz archive before they are
+replaced; the 5 newest of these archives are kept, backup rotation leaves
+them alone. Stop serve before restoring.`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRun:  func(cmd *cobra.Command, args []string) { loadConfig() },
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := backupDir(cmd)
+		path := findBackup(dir, args[0])
+
+		manifest, _, err := backup.Verify(path)
+		if err != nil {
+			fmt.Printf("%s The backup cannot be restored: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		if manifest.Source != "" && filepath.Clean(manifest.Source) != filepath.Clean(workspace.DB) && !force {
+			fmt.Printf("%s The backup is of %s, not of %s; give --force to restore it anyway\n", promptui.IconBad, manifest.Source, workspace.DB)
+			os.Exit(1)
+		}
+
+		if safety, ok := backupCurrent(dir); ok {
+			fmt.Printf("%s Backed up the current files to %s\n", promptui.IconGood, safety.Path)
+			removed, err := backup.Prune(dir, preRestoreLabel, preRestoreBackups)
+			if err != nil {
+				fmt.Printf("%s Failed to remove older pre-restore backups: %v\n", promptui.IconWarn, err)
+			}
+			for _, b := range removed {
+				fmt.Printf("  Removed %s\n", b.Name())
+			}
+		}
+		if err := backup.Restore(path, workspace.DB, workspace.CSV); err != nil {
+			fmt.Printf("%s Failed to restore: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s Restored the backup of %s\n", promptui.IconGood, manifest.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+		fmt.Printf("  Database: %s\n", workspace.DB)
+		for _, file := range manifest.Files {
+			if file.Name == backup.CSVFile {
+				fmt.Printf("  CSV file: %s\n", workspace.CSV)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	backupCmd.AddCommand(backupListCmd)
+
+	backupCmd.PersistentFlags().String("dir", "", "Backup directory (default is the backup directory of the workspace)")
+	backupCmd.Flags().Int("keep-daily", 7, "Number of days to keep the newest backup of")
+	backupCmd.Flags().Int("keep-weekly", 4, "Number of weeks to keep the newest backup of")
+	restoreCmd.Flags().String("dir", "", "Backup directory to find the backup in (default is the backup directory of the workspace)")
+	restoreCmd.Flags().Bool("force", false, "Restore a backup made from another database")
+}
+
+// backupDir returns the --dir flag of the command, or the backup directory of
+// the workspace.
+func backupDir(cmd *cobra.Command) string {
+	dir, _ := cmd.Flags().GetString("dir")
+	if dir == "" {
+		return workspace.Backups
+	}
+	absolute, err := filepath.Abs(dir)
+	if err != nil {
+		fmt.Printf("%s Invalid --dir path: %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	return absolute
+}
+
+// findBackup returns the archive named by ref: a path, or the name of an
+// archive of dir with or without its .tar.gz extension.
+func findBackup(dir string, ref string) string {
+	candidates := []string{ref, filepath.Join(dir, ref)}
+	if !strings.HasSuffix(ref, ".tar.gz") {
+		candidates = append(candidates, filepath.Join(dir, ref+".tar.gz"))
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	fmt.Printf("%s No backup %s, see backup list\n", promptui.IconBad, ref)
+	os.Exit(1)
+	return ""
+}
+
+// backupCurrent backs up the files of the workspace before a restore,
+// exiting when they cannot be backed up. It reports false when there are no
+// files to back up.
+func backupCurrent(dir string) (backup.Backup, bool) {
+	if !fileExists(workspace.DB) && !fileExists(workspace.CSV) {
+		return backup.Backup{}, false
+	}
+
+	db, err := database.Open(workspace.DB)
+	if err != nil {
+		fmt.Printf("%s Failed to back up the current files: %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+	safety, err := backup.Create(dir, db, workspace.CSV, time.Now(), preRestoreLabel)
+	if err != nil {
+		fmt.Printf("%s Failed to back up the current files: %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	return safety, true
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// formatSize formats a size in bytes for people.
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
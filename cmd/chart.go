@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+const chartHeight = 10
+
+// chartDay holds the number of tasks on a given day: Scope were created by
+// then, Completed were done by then and Remaining are still open.
+type chartDay struct {
+	Day       time.Time
+	Scope     int
+	Completed int
+	Remaining int
+}
+
+// chartCmd represents the chart command
+var chartCmd = &cobra.Command{
+	Use:   "chart burndown|burnup|heatmap",
+	Short: "Draw charts of task progress in the terminal",
+	Long: `Draw a chart from when tasks were created and changed status, as recorded
+in the task history:
+
+  burndown  open tasks per day
+  burnup    created (scope) and completed tasks per day
+  heatmap   completed tasks per day, one column per week
+
+Use --svg to also write the chart to a standalone SVG file.`,
+	ValidArgs: []string{"burndown", "burnup", "heatmap"},
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	PreRun:    requireLocalBackend,
+	Run: func(cmd *cobra.Command, args []string) {
+		days, _ := cmd.Flags().GetInt("days")
+		svgPath, _ := cmd.Flags().GetString("svg")
+		if days < 1 {
+			fmt.Printf("%s --days must be at least 1\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		store := promptStore("Which database should we chart?")
+		flows, err := getTaskFlows(store)
+		if err != nil {
+			fmt.Printf("%s Failed to read tasks: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		now := time.Now()
+		var svg string
+		switch args[0] {
+		case "burndown":
+			series := buildChartSeries(flows, days, now)
+			fmt.Print(renderBurndown(series))
+			svg = burndownSVG(series)
+		case "burnup":
+			series := buildChartSeries(flows, days, now)
+			fmt.Print(renderBurnup(series))
+			svg = burnupSVG(series)
+		case "heatmap":
+			counts := completionsPerDay(flows)
+			fmt.Print(renderHeatmap(counts, days, now))
+			svg = heatmapSVG(counts, days, now)
+		}
+
+		if svgPath != "" {
+			if err := os.WriteFile(svgPath, []byte(svg), 0644); err != nil {
+				fmt.Printf("%s Failed to write %s: %v\n", promptui.IconBad, svgPath, err)
+				os.Exit(1)
+			}
+			fmt.Printf("%s Chart written to %s\n", promptui.IconGood, svgPath)
+		}
+	},
+}
+
+func init() {
+	chartCmd.Flags().Int("days", 30, "Number of days to chart")
+	chartCmd.Flags().String("svg", "", "Also write the chart to this SVG file")
+	rootCmd.AddCommand(chartCmd)
+}
+
+// startOfDay returns local midnight of the day of t.
+func startOfDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// buildChartSeries counts scope, completed and remaining tasks at the end of
+// each of the last days, today included. Whether a task was completed on a
+// day is replayed from its status history, so reopened tasks count as open
+// again.
+func buildChartSeries(flows []taskFlow, days int, now time.Time) []chartDay {
+	first := startOfDay(now).AddDate(0, 0, -(days - 1))
+	series := make([]chartDay, days)
+	for i := range series {
+		day := first.AddDate(0, 0, i)
+		end := day.AddDate(0, 0, 1)
+		series[i].Day = day
+
+		for _, flow := range flows {
+			if !flow.Task.CreatedAt.Before(end) {
+				continue
+			}
+			series[i].Scope++
+			if flow.statusAt(end) == "completed" {
+				series[i].Completed++
+			}
+		}
+		series[i].Remaining = series[i].Scope - series[i].Completed
+	}
+	return series
+}
+
+// completionsPerDay counts completed tasks by local day.
+func completionsPerDay(flows []taskFlow) map[time.Time]int {
+	counts := make(map[time.Time]int)
+	for _, flow := range flows {
+		if flow.CompletedAt != nil {
+			counts[startOfDay(*flow.CompletedAt)]++
+		}
+	}
+	return counts
+}
+
+// scaleHeight converts value into a height in eighths of rows.
+func scaleHeight(value int, max int) int {
+	if max == 0 {
+		return 0
+	}
+	return int(math.Round(float64(value) / float64(max) * chartHeight * 8))
+}
+
+// renderBars draws one column per value using eighth blocks for the tops.
+func renderBars(values []int, max int) []string {
+	blocks := []rune(" ▁▂▃▄▅▆▇█")
+	lines := make([]string, chartHeight)
+	for row := 0; row < chartHeight; row++ {
+		var line strings.Builder
+		for _, value := range values {
+			fill := scaleHeight(value, max) - (chartHeight-1-row)*8
+			switch {
+			case fill >= 8:
+				line.WriteRune(blocks[8])
+			case fill > 0:
+				line.WriteRune(blocks[fill])
+			default:
+				line.WriteRune(' ')
+			}
+		}
+		lines[row] = line.String()
+	}
+	return lines
+}
+
+// frameChart adds a y axis labelled with max and a date axis below the rows.
+func frameChart(title string, rows []string, max int, series []chartDay, legend string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s\n\n", title)
+	width := len(fmt.Sprint(max))
+	for i, row := range rows {
+		label := ""
+		switch i {
+		case 0:
+			label = fmt.Sprint(max)
+		case len(rows) - 1:
+			label = "0"
+		}
+		fmt.Fprintf(&out, "%*s ┤%s\n", width, label, row)
+	}
+	fmt.Fprintf(&out, "%*s └%s\n", width, "", strings.Repeat("─", len(series)))
+
+	first := series[0].Day.Format("Jan 02")
+	last := series[len(series)-1].Day.Format("Jan 02")
+	gap := len(series) - len(first) - len(last)
+	if gap < 1 {
+		gap = 1
+	}
+	fmt.Fprintf(&out, "%*s  %s%s%s\n", width, "", first, strings.Repeat(" ", gap), last)
+	if legend != "" {
+		fmt.Fprintf(&out, "\n%s\n", legend)
+	}
+	return out.String()
+}
+
+func renderBurndown(series []chartDay) string {
+	values := make([]int, len(series))
+	max := 0
+	for i, day := range series {
+		values[i] = day.Remaining
+		if day.Remaining > max {
+			max = day.Remaining
+		}
+	}
+	return frameChart("Burndown: open tasks per day", renderBars(values, max), max, series, "")
+}
+
+func renderBurnup(series []chartDay) string {
+	max := 0
+	for _, day := range series {
+		if day.Scope > max {
+			max = day.Scope
+		}
+	}
+
+	rows := make([]string, chartHeight)
+	for row := 0; row < chartHeight; row++ {
+		var line strings.Builder
+		level := chartHeight - row
+		for _, day := range series {
+			switch {
+			case (scaleHeight(day.Completed, max)+4)/8 >= level:
+				line.WriteRune('█')
+			case (scaleHeight(day.Scope, max)+4)/8 >= level:
+				line.WriteRune('░')
+			default:
+				line.WriteRune(' ')
+			}
+		}
+		rows[row] = line.String()
+	}
+	return frameChart("Burnup: created and completed tasks", rows, max, series, "█ completed  ░ scope")
+}
+
+// heatmapWeeks returns the monday of the first week shown and the number of
+// weeks needed to cover the last days.
+func heatmapWeeks(days int, now time.Time) (time.Time, int) {
+	today := startOfDay(now)
+	first := today.AddDate(0, 0, -(days - 1))
+	first = first.AddDate(0, 0, -((int(first.Weekday()) + 6) % 7))
+	weeks := int(today.Sub(first).Hours()/24)/7 + 1
+	return first, weeks
+}
+
+func renderHeatmap(counts map[time.Time]int, days int, now time.Time) string {
+	shades := []rune("·░▒▓█")
+	first, weeks := heatmapWeeks(days, now)
+	today := startOfDay(now)
+
+	max := 0
+	for day, count := range counts {
+		if !day.Before(first) && count > max {
+			max = count
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "Completed tasks per day since %s\n\n", first.Format("Jan 02"))
+	for weekday, name := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		fmt.Fprintf(&out, "%s ", name)
+		for week := 0; week < weeks; week++ {
+			day := first.AddDate(0, 0, week*7+weekday)
+			shade := ' '
+			if !day.After(today) {
+				shade = '·'
+				if count := counts[day]; count > 0 {
+					shade = shades[int(math.Ceil(float64(count)/float64(max)*float64(len(shades)-1)))]
+				}
+			}
+			out.WriteRune(shade)
+			out.WriteRune(shade)
+		}
+		out.WriteString("\n")
+	}
+	fmt.Fprintf(&out, "\n· none  ░ fewer  █ %d\n", max)
+	return out.String()
+}
+
+// svgDocument wraps the shapes in a standalone SVG document.
+func svgDocument(width int, height int, title string, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">
+<rect width="100%%" height="100%%" fill="#ffffff"/>
+<text x="20" y="24" font-size="16" font-weight="bold">%s</text>
+%s</svg>
+`, width, height, width, height, title, body)
+}
+
+// svgBars draws the layers of a bar chart, each layer over the previous one.
+func svgBars(series []chartDay, max int, layers []func(chartDay) int, colors []string) (int, int, string) {
+	const barWidth, gap, plotHeight, left, top = 16, 4, 240, 50, 40
+	width := left + len(series)*(barWidth+gap) + 20
+	height := top + plotHeight + 40
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#888\"/>\n", left-4, top+plotHeight, width-20, top+plotHeight)
+	fmt.Fprintf(&body, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%d</text>\n", left-8, top+8, max)
+	fmt.Fprintf(&body, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">0</text>\n", left-8, top+plotHeight)
+
+	for i, day := range series {
+		x := left + i*(barWidth+gap)
+		for l, layer := range layers {
+			h := 0
+			if max > 0 {
+				h = layer(day) * plotHeight / max
+			}
+			fmt.Fprintf(&body, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"><title>%s: %d</title></rect>\n",
+				x, top+plotHeight-h, barWidth, h, colors[l], day.Day.Format("2006-01-02"), layer(day))
+		}
+		if i == 0 || i == len(series)-1 || day.Day.Weekday() == time.Monday {
+			fmt.Fprintf(&body, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%s</text>\n",
+				x+barWidth/2, top+plotHeight+18, day.Day.Format("Jan 02"))
+		}
+	}
+	return width, height, body.String()
+}
+
+func burndownSVG(series []chartDay) string {
+	max := 0
+	for _, day := range series {
+		if day.Remaining > max {
+			max = day.Remaining
+		}
+	}
+	width, height, body := svgBars(series, max,
+		[]func(chartDay) int{func(d chartDay) int { return d.Remaining }},
+		[]string{"#e4572e"})
+	return svgDocument(width, height, "Burndown: open tasks per day", body)
+}
+
+func burnupSVG(series []chartDay) string {
+	max := 0
+	for _, day := range series {
+		if day.Scope > max {
+			max = day.Scope
+		}
+	}
+	width, height, body := svgBars(series, max,
+		[]func(chartDay) int{func(d chartDay) int { return d.Scope }, func(d chartDay) int { return d.Completed }},
+		[]string{"#c9d6df", "#29a36a"})
+	return svgDocument(width, height, "Burnup: created and completed tasks", body)
+}
+
+func heatmapSVG(counts map[time.Time]int, days int, now time.Time) string {
+	const cell, gap, left, top = 14, 3, 50, 40
+	first, weeks := heatmapWeeks(days, now)
+	today := startOfDay(now)
+
+	max := 0
+	for day, count := range counts {
+		if !day.Before(first) && count > max {
+			max = count
+		}
+	}
+
+	var body strings.Builder
+	for weekday, name := range []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"} {
+		fmt.Fprintf(&body, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\">%s</text>\n", left-6, top+weekday*(cell+gap)+cell-2, name)
+		for week := 0; week < weeks; week++ {
+			day := first.AddDate(0, 0, week*7+weekday)
+			if day.After(today) {
+				continue
+			}
+			opacity := 0.08
+			if count := counts[day]; count > 0 && max > 0 {
+				opacity = 0.25 + 0.75*float64(count)/float64(max)
+			}
+			fmt.Fprintf(&body, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"2\" fill=\"#29a36a\" fill-opacity=\"%.2f\"><title>%s: %d</title></rect>\n",
+				left+week*(cell+gap), top+weekday*(cell+gap), cell, cell, opacity, day.Format("2006-01-02"), counts[day])
+		}
+	}
+
+	width := left + weeks*(cell+gap) + 20
+	height := top + 7*(cell+gap) + 20
+	return svgDocument(width, height, "Completed tasks per day", body.String())
+}
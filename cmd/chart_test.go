@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unf6/testing/models"
+)
+
+func TestBuildChartSeries(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.Local)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.Local) }
+	at := func(d int) *time.Time {
+		t := day(d)
+		return &t
+	}
+
+	reopened := taskFlow{
+		Task: models.Task{Status: "pending", CreatedAt: day(9)},
+		Statuses: []statusChange{
+			{At: day(10), From: "pending", To: "completed"},
+			{At: day(12), From: "completed", To: "pending"},
+		},
+	}
+	// Completed without history, at its last update.
+	imported := taskFlow{Task: models.Task{Status: "completed", CreatedAt: day(11)}, CompletedAt: at(12)}
+	created := taskFlow{Task: models.Task{Status: "pending", CreatedAt: day(13)}}
+
+	tests := []struct {
+		name      string
+		flows     []taskFlow
+		scope     []int
+		completed []int
+	}{
+		{name: "reopened", flows: []taskFlow{reopened}, scope: []int{1, 1, 1, 1}, completed: []int{1, 1, 0, 0}},
+		{name: "completed without history", flows: []taskFlow{imported}, scope: []int{0, 1, 1, 1}, completed: []int{0, 0, 1, 1}},
+		{name: "created today", flows: []taskFlow{created}, scope: []int{0, 0, 0, 1}, completed: []int{0, 0, 0, 0}},
+		{name: "all", flows: []taskFlow{reopened, imported, created}, scope: []int{1, 2, 2, 3}, completed: []int{1, 1, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := buildChartSeries(tt.flows, 4, now)
+			var scope, completed []int
+			for i, d := range series {
+				if want := day(10 + i); !d.Day.Equal(startOfDay(want)) {
+					t.Errorf("day %d = %v, want %v", i, d.Day, startOfDay(want))
+				}
+				if d.Remaining != d.Scope-d.Completed {
+					t.Errorf("day %d: remaining %d of scope %d with %d completed", i, d.Remaining, d.Scope, d.Completed)
+				}
+				scope = append(scope, d.Scope)
+				completed = append(completed, d.Completed)
+			}
+			if !reflect.DeepEqual(scope, tt.scope) {
+				t.Errorf("scope = %v, want %v", scope, tt.scope)
+			}
+			if !reflect.DeepEqual(completed, tt.completed) {
+				t.Errorf("completed = %v, want %v", completed, tt.completed)
+			}
+		})
+	}
+}
+
+func TestRenderHeatmap(t *testing.T) {
+	// A Wednesday: the heatmap shows the weeks of March 4 and 11.
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.Local)
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		name   string
+		counts map[time.Time]int
+		rows   map[string]string
+		legend string
+	}{
+		{
+			name:   "none",
+			counts: map[time.Time]int{},
+			rows:   map[string]string{"Mon": "Mon ····", "Wed": "Wed ····", "Thu": "Thu ··  "},
+			legend: "█ 0",
+		},
+		{
+			name: "shaded by the maximum",
+			// The completions before the first week do not count.
+			counts: map[time.Time]int{day(1): 10, day(6): 1, day(11): 2, day(13): 4},
+			rows:   map[string]string{"Mon": "Mon ··▒▒", "Wed": "Wed ░░██", "Sun": "Sun ··  "},
+			legend: "█ 4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := renderHeatmap(tt.counts, 7, now)
+			lines := strings.Split(out, "\n")
+			if !strings.Contains(lines[0], "Mar 04") {
+				t.Errorf("title = %q, want the week of Mar 04", lines[0])
+			}
+			for name, want := range tt.rows {
+				found := false
+				for _, line := range lines {
+					if strings.HasPrefix(line, name+" ") {
+						found = true
+						if line != want {
+							t.Errorf("row %q, want %q", line, want)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("no %s row in\n%s", name, out)
+				}
+			}
+			if !strings.HasSuffix(out, tt.legend+"\n") {
+				t.Errorf("legend of\n%s\nwant %q", out, tt.legend)
+			}
+		})
+	}
+}
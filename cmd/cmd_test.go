@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/unf6/testing/pkg/config"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+)
+
+// useTestWorkspace points the commands at a new database and CSV file with
+// the default settings, as loadConfig would.
+func useTestWorkspace(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("TASKS_CLI_ACTOR", "tester")
+
+	workspace = config.Workspace{
+		Name:    config.DefaultWorkspace,
+		DB:      filepath.Join(dir, "tasks.db"),
+		CSV:     filepath.Join(dir, "tasks.csv"),
+		Backups: filepath.Join(dir, "backups"),
+	}
+	cfg = config.Defaults()
+	database.ConnectDB(workspace.DB)
+	t.Cleanup(database.CloseDB)
+
+	previous := keyring
+	keyring = &encryption.Keyring{}
+	t.Cleanup(func() {
+		keyring = previous
+		operationID = 0
+		operationActor = ""
+	})
+}
+
+// encryptTestWorkspace encrypts the database of the test workspace with the
+// passphrase, which the keyring is given.
+func encryptTestWorkspace(t *testing.T, passphrase string) {
+	t.Helper()
+	key, err := encryption.NewKey(passphrase)
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	sqlite, _ := encryptableStores()
+	if err := sqlite.Encrypt(key); err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	keyring = &encryption.Keyring{Passphrase: func() (string, error) { return passphrase, nil }}
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/config"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change the settings of the config file",
+	Long: `Show and change the settings of the config file, config.yaml in the config
+directory unless --config or TASKS_CLI_CONFIG points elsewhere:
+
+  workspace     workspace used without --workspace, see the workspace command
+  backend       store used without asking: sqlite, csv or remote
+  output        default output format of the listing commands: table or json
+  date_format   "relative" or a Go time layout such as "2006-01-02 15:04"
+  columns       columns of the task table, e.g. id,title,status
+  colors        auto, always or never
+  editor        command descriptions are edited with
+  key_cache     how long the key of an encrypted store is remembered, e.g. 15m
+  remote.url    tasks-cli server of the remote backend
+  remote.token  API token of the remote backend
+
+Profiles override the top level settings they set: "config set --profile work
+backend csv" changes the work profile, which is used with --profile work or
+once selected with "config set profile work". Every setting can also be
+overridden with an environment variable such as TASKS_CLI_BACKEND or
+TASKS_CLI_REMOTE_URL.`,
+	PersistentPreRun:  loadSettingsOnly,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the settings in effect",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := loadConfigFile()
+		settings, err := file.Resolve(cfgProfile)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconWarn, err)
+		}
+
+		fmt.Printf("Config file: %s\n", cfgFile)
+		if profile := file.SelectProfile(cfgProfile); profile != "" {
+			fmt.Printf("Profile: %s\n", profile)
+		}
+		fmt.Println()
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE")
+		for _, key := range config.Keys {
+			value, _ := settings.Get(key)
+			if key == "remote.token" && len(value) > len(tokenPrefix)+8 {
+				value = value[:len(tokenPrefix)+8] + "…"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", key, value)
+		}
+		w.Flush()
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:       "get <key>",
+	Short:     "Print the value in effect of a setting",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: append([]string{"profile"}, config.Keys...),
+	Run: func(cmd *cobra.Command, args []string) {
+		file := loadConfigFile()
+		if args[0] == "profile" {
+			fmt.Println(file.SelectProfile(cfgProfile))
+			return
+		}
+
+		settings, err := file.Resolve(cfgProfile)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconWarn, err)
+		}
+		value, err := settings.Get(args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting in the config file, an empty value unsets it",
+	Long: `Change a setting in the config file, or in the profile selected with
+--profile. An empty value unsets it. "config set profile <name>" selects the
+profile used by default.`,
+	Args:      cobra.ExactArgs(2),
+	ValidArgs: append([]string{"profile"}, config.Keys...),
+	Run: func(cmd *cobra.Command, args []string) {
+		key, value := args[0], args[1]
+		file := loadConfigFile()
+
+		if err := setSetting(&file, key, value); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if err := config.Save(cfgFile, file); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if value == "" {
+			fmt.Printf("%s Unset %s\n", promptui.IconGood, key)
+		} else {
+			fmt.Printf("%s Set %s to %s\n", promptui.IconGood, key, value)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// setSetting changes a setting of the file, or of the profile selected with
+// --profile. The profile key selects the default profile.
+func setSetting(file *config.Config, key string, value string) error {
+	switch {
+	case key == "profile":
+		if _, ok := file.Profiles[value]; value != "" && !ok {
+			return fmt.Errorf("no profile named %q, create it with config set --profile %s <key> <value>", value, value)
+		}
+		file.Profile = value
+	case cfgProfile != "":
+		profile := file.Profiles[cfgProfile]
+		if err := profile.Set(key, value); err != nil {
+			return err
+		}
+		if file.Profiles == nil {
+			file.Profiles = make(map[string]config.Settings)
+		}
+		file.Profiles[cfgProfile] = profile
+	default:
+		return file.Settings.Set(key, value)
+	}
+	return nil
+}
+
+// loadSettingsOnly replaces loadConfig and the database connection for the
+// commands managing the config file: they must work when the settings are
+// invalid to fix them, so errors are left to the commands showing them.
+func loadSettingsOnly(cmd *cobra.Command, args []string) {
+	if cfgFile == "" {
+		cfgFile = config.Path()
+	}
+	if file, err := config.Load(cfgFile); err == nil {
+		cfg, _ = file.Resolve(cfgProfile)
+	}
+	applyColors()
+	configureKeyring()
+}
+
+// loadConfigFile reads the config file selected with --config, exiting when
+// it cannot be parsed.
+func loadConfigFile() config.Config {
+	file, err := config.Load(cfgFile)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	return file
+}
+
+// outputFormat returns the --format flag of the command, or the output
+// setting when the flag is not given.
+func outputFormat(cmd *cobra.Command) string {
+	format, _ := cmd.Flags().GetString("format")
+	if !cmd.Flags().Changed("format") && cfg.Output != "" {
+		return cfg.Output
+	}
+	return format
+}
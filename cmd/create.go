@@ -2,26 +2,29 @@ package cmd
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"fmt"
 	"os"
-	"strconv"
+	"strings"
 	"time"
-    "path/filepath"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
 	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
 )
 
 var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new task",
-	Long:  `Create a new task by providing a title, optional description, and optional status.`,
+	Long:  `Create a new task by providing a title, optional description, and optional status.
+The project and tags of the task group its tracked time in the timesheet.`,
 	Args:  cobra.MaximumNArgs(1),
+	Annotations: writeCommand,
 	Run: func(cmd *cobra.Command, args []string) {
 		var title, description string
-		db := database.GetDB()
+		project, _ := cmd.Flags().GetString("project")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
 
 		if len(args) > 0 {
 			title = args[0]
@@ -58,29 +61,44 @@ var createCmd = &cobra.Command{
 
 		description = descriptionText
 
-		saveOptionPrompt := promptui.Select{
-			Label:     "Where do you wish to save the task",
-			Items:     []string{"Database (sqlite)", "CSV File"},
-			CursorPos: 0,
-		}
+		saveOption := configuredChoice()
+		if saveOption == "" {
+			saveOptionPrompt := promptui.Select{
+				Label:     "Where do you wish to save the task",
+				Items:     []string{"Database (sqlite)", "CSV File"},
+				CursorPos: 0,
+			}
 
-		_, saveOption, saveOptionErr := saveOptionPrompt.Run()
+			var saveOptionErr error
+			_, saveOption, saveOptionErr = saveOptionPrompt.Run()
 
-		if saveOptionErr != nil {
-			fmt.Printf("%s Error: %v\n", promptui.IconBad, saveOptionErr)
-			os.Exit(1)
+			if saveOptionErr != nil {
+				fmt.Printf("%s Error: %v\n", promptui.IconBad, saveOptionErr)
+				os.Exit(1)
+			}
 		}
 
 		switch saveOption {
+		case "Remote":
+			saveToRemote(Task{
+				Title:       title,
+				Description: description,
+				Project:     project,
+				Tags:        tags,
+			})
 		case "CSV File":
 			saveToCSVFile(Task{
 				Title:       title,
 				Description: description,
+				Project:     project,
+				Tags:        tags,
 			})
 		default:
-			saveToSqliteDB(db, Task{
+			saveToSqliteDB(database.GetDB(), Task{
 				Title:       title,
 				Description: description,
+				Project:     project,
+				Tags:        tags,
 			})
 		}
 		fmt.Printf("%s Task created successfully!\n", promptui.IconGood)
@@ -88,96 +106,66 @@ var createCmd = &cobra.Command{
 }
 
 func init() {
+	createCmd.Flags().String("project", "", "Project of the task")
+	createCmd.Flags().StringSlice("tag", nil, "Tag of the task, repeat it or separate tags with commas")
 	rootCmd.AddCommand(createCmd)
 }
 
 type Task struct {
 	Title       string
 	Description string
+	Project     string
+	Tags        []string
 }
 
 func saveToSqliteDB(db *sql.DB, task Task) {
-	createTask := `
-	INSERT INTO tasks(title, description)
-	VALUES(?, ?)
-`
-	if _, taskCreateErr := db.Exec(createTask, task.Title, task.Description); taskCreateErr != nil {
+	store := &tasks.SQLiteStore{DB: db, OnChange: recordChange, Keys: keyring.Key}
+
+	now := time.Now().UTC()
+	if _, taskCreateErr := store.Add(models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      "pending",
+		Project:     strings.TrimSpace(task.Project),
+		Tags:        tasks.NormalizeTags(task.Tags),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}); taskCreateErr != nil {
 		fmt.Printf("Failed to create the task: %v", taskCreateErr)
 		os.Exit(1)
 	}
 }
 
-func saveToCSVFile(task Task) {
-
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
-
-	// Create the configuration directory if it doesn't exist
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err = os.MkdirAll(configDir, 0755)
-		if err != nil {
-			fmt.Printf("Failed to create config directory: %v\n", err)
-			os.Exit(1)
-		}
-	}
-
-	// Define the CSV file path
-	csvFilePath := filepath.Join(configDir, "tasks.csv")
-
-	// Open CSV File in append mode create if doesn't exists
-	file, err := os.OpenFile(csvFilePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
-
-	if err != nil {
-		fmt.Printf("Failed to create/open CSV file: %v", err)
+func saveToRemote(task Task) {
+	now := time.Now().UTC()
+	if _, err := getRemoteStore().Add(models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      "pending",
+		Project:     strings.TrimSpace(task.Project),
+		Tags:        tasks.NormalizeTags(task.Tags),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}); err != nil {
+		fmt.Printf("Failed to create the task on the server: %v", err)
 		os.Exit(1)
 	}
-	defer file.Close()
-
-	// check if CSV file is empty to write headers
-	stat, statErr := file.Stat()
-
-	if statErr != nil {
-		fmt.Printf("Cannot get file info: %v", statErr)
-	}
-
-	nextId := 1
-	if stat.Size() <= 0 {
-		// File empty write headers.
-		writer := csv.NewWriter(file)
-		csvHeaders := []string{"ID", "TITLE", "DESCRIPTION", "STATUS", "CREATED AT", "UPDATED AT"}
-
-		if errCSVHeaders := writer.Write(csvHeaders); errCSVHeaders != nil {
-			fmt.Printf("Failed to write headers for the CSV File: %v", errCSVHeaders)
-			os.Exit(1)
-		}
-		writer.Flush()
-	} else {
-		// Read CSV File
-		reader := csv.NewReader(file)
-		records, errRecords := reader.ReadAll()
-
-		if errRecords != nil {
-			fmt.Printf("Error loading CSV File records: %v", errRecords)
-			os.Exit(1)
-		}
-
-		// Filter headers first
-		csvRecords := records[1:]
-		if len(csvRecords) > 0 {
-			lastRecord := csvRecords[len(records)-1]
-			lastIndex, errLastIndex := strconv.Atoi(lastRecord[0])
+}
 
-			if errLastIndex != nil {
-				fmt.Printf("Failed to parse the last record index: %v", errLastIndex)
-				os.Exit(1)
-			}
-			nextId = lastIndex + 1
-		}
+func saveToCSVFile(task Task) {
+	store := newCSVStore()
+
+	now := time.Now().UTC()
+	if _, err := store.Add(models.Task{
+		Title:       task.Title,
+		Description: task.Description,
+		Status:      "pending",
+		Project:     strings.TrimSpace(task.Project),
+		Tags:        tasks.NormalizeTags(task.Tags),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}); err != nil {
+		fmt.Printf("Failed to save the task to the CSV file: %v", err)
+		os.Exit(1)
 	}
-
-	newRecord := append([]string{strconv.Itoa(nextId)}, task.Title, task.Description, "pending", time.Now().UTC().String(), time.Now().UTC().String())
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	writer.Write(newRecord)
-}
\ No newline at end of file
+}
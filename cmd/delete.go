@@ -4,22 +4,21 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
-	"encoding/csv"
 	"fmt"
 	"os"
-	"strconv"
-    "path/filepath"
+	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/models"
 )
 
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Remove a task",
-	Long:  `Remove a task by its ID`,
+	Use:         "delete",
+	Short:       "Move a task to the trash",
+	Long:        `Move a task to the trash by its ID. Use the trash command to restore or purge it.`,
+	Annotations: writeCommand,
 	Run: func(cmd *cobra.Command, args []string) {
 		id, err := cmd.Flags().GetString("id")
 
@@ -27,36 +26,41 @@ var deleteCmd = &cobra.Command{
 			fmt.Printf("Failed to get id flag: %v", err)
 			os.Exit(1)
 		}
-		prompt := promptui.Select{
-			Label:     "Where would you like to delete from?",
-			Items:     []string{"Database (sqlite)", "CSV File"},
-			CursorPos: 0,
+		choice := configuredChoice()
+		if choice == "" {
+			prompt := promptui.Select{
+				Label:     "Where would you like to delete from?",
+				Items:     []string{"Database (sqlite)", "CSV File"},
+				CursorPos: 0,
+			}
+
+			var promptErr error
+			_, choice, promptErr = prompt.Run()
+
+			if promptErr != nil {
+				fmt.Printf("%s Error: %v\n", promptui.IconBad, promptErr)
+				os.Exit(1)
+			}
 		}
 
-		_, choice, promptErr := prompt.Run()
-
-		if promptErr != nil {
-			fmt.Printf("%s Error: %v\n", promptui.IconBad, promptErr)
-			os.Exit(1)
-		}
-
-		parsedInt, parsedErr := strconv.Atoi(id)
-		if parsedErr != nil {
-			fmt.Printf("%s ID needs to be an interger %v\n", promptui.IconBad, parsedErr)
+		if err := validateIDInput(id); err != nil {
+			fmt.Printf("%s ID needs to be an integer or a UID prefix\n", promptui.IconBad)
 			os.Exit(1)
 		}
 
 		switch choice {
+		case "Remote":
+			deleteFromRemote(id)
 		case "CSV File":
-			deleteFromCSVFile(parsedInt)
+			deleteFromCSVFile(id)
 		default:
-			deleteFromDB(parsedInt)
+			deleteFromDB(id)
 		}
 	},
 }
 
 func init() {
-	deleteCmd.Flags().StringP("id", "d", "", "Remove a task by its ID")
+	deleteCmd.Flags().StringP("id", "d", "", "Remove a task by its ID or UID prefix")
 	deleteCmd.MarkFlagRequired("id")
 
 	deleteCmd.RegisterFlagCompletionFunc("id", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -65,123 +69,63 @@ func init() {
 	rootCmd.AddCommand(deleteCmd)
 }
 
-func deleteFromDB(id int) {
-	db := database.GetDB()
-
-	query := `DELETE FROM tasks WHERE id=?`
+func deleteFromDB(id string) {
+	store := newSQLiteStore()
 
-	result, resultsErr := db.Exec(query, id)
-
-	if resultsErr != nil {
-		fmt.Printf("%v Failed to delete task from Database (sqlite): %v", promptui.IconBad, resultsErr)
-		os.Exit(1)
-	}
-
-	// Check if any rows were affected (i.e., a record was deleted)
-	rowsAffected, err := result.RowsAffected()
+	task, err := findTask(store, id)
 	if err != nil {
-		fmt.Printf("%v Error getting rows affected: %v\n", promptui.IconBad, err)
-		os.Exit(1)
-	}
-
-	if rowsAffected > 0 {
-		fmt.Printf("%v Successfully deleted task with ID %d\n", promptui.IconGood, id)
-		fmt.Printf("%v Database (sqlite) Data updated\n", promptui.IconGood)
-	} else {
-		fmt.Printf("%v No task found with ID %d to delete\n", promptui.IconGood, id)
-	}
-}
-
-func deleteFromCSVFile(id int) {
-
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
-
-	// Create the configuration directory if it doesn't exist
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err = os.MkdirAll(configDir, 0755)
-		if err != nil {
-			fmt.Printf("Failed to create config directory: %v\n", err)
-			os.Exit(1)
-		}
+		fmt.Printf("%v %v\n", promptui.IconBad, err)
+		return
 	}
 
-	// Define the CSV file path
-	csvFilePath := filepath.Join(configDir, "tasks.csv")
-
-	// Open file with read and write permissions
-	file, fileErr := os.OpenFile(csvFilePath, os.O_RDWR|os.O_CREATE, 0644)
-	if fileErr != nil {
-		fmt.Printf("%v Failed to create/open CSV file: %v", promptui.IconBad, fileErr)
+	if err := trashTask(store, task); err != nil {
+		fmt.Printf("%v Failed to delete task from Database (sqlite): %v", promptui.IconBad, err)
 		os.Exit(1)
 	}
-	defer file.Close()
 
-	// Read CSV File
-	reader := csv.NewReader(file)
-	records, errRecords := reader.ReadAll()
-	if errRecords != nil {
-		fmt.Printf("%v Error loading CSV File records: %v", promptui.IconBad, errRecords)
-		os.Exit(1)
-	}
+	fmt.Printf("%v Successfully moved task with ID %d to the trash\n", promptui.IconGood, task.ID)
+	fmt.Printf("%v Database (sqlite) Data updated\n", promptui.IconGood)
+}
 
-	data := getDataFromCSVFile(records)
+func deleteFromRemote(id string) {
+	store := getRemoteStore()
 
-	if len(data) <= 0 {
-		fmt.Printf("%v No tasks found in the CSV file\n", promptui.IconGood)
+	task, err := findTask(store, id)
+	if err != nil {
+		fmt.Printf("%v %v\n", promptui.IconBad, err)
 		return
 	}
 
-	// Find and remove the task with matching ID
-	newCSVData := []DBTask{}
-	found := false
-	for _, content := range data {
-		if content.ID == id {
-			found = true
-			continue // Skip this record
-		}
-		newCSVData = append(newCSVData, content)
+	if err := trashTask(store, task); err != nil {
+		fmt.Printf("%v Failed to delete task on the server: %v", promptui.IconBad, err)
+		os.Exit(1)
 	}
 
-	if !found {
-		fmt.Printf("%v No task found with ID %d to delete.\n", promptui.IconGood, id)
-		return
-	}
+	fmt.Printf("%v Successfully moved task with ID %d to the trash\n", promptui.IconGood, task.ID)
+	fmt.Printf("%v %s Data updated\n", promptui.IconGood, store.Name())
+}
 
-	// Convert tasks back to CSV records
-	var newRecords [][]string
-	// Add headers
-	newRecords = append(newRecords, []string{"ID", "TITLE", "DESCRIPTION", "STATUS", "CREATED AT", "UPDATED AT"})
-	for _, task := range newCSVData {
-		record := []string{
-			strconv.Itoa(task.ID),
-			task.Title,
-			task.Description,
-			task.Status,
-			task.CreatedAt,
-			task.UpdatedAt,
-		}
-		newRecords = append(newRecords, record)
-	}
+func deleteFromCSVFile(id string) {
+	store := newCSVStore()
 
-	// Truncate the file and write from beginning
-	if err := file.Truncate(0); err != nil {
-		fmt.Printf("%v Error truncating file: %v", promptui.IconBad, err)
-		return
-	}
-	if _, err := file.Seek(0, 0); err != nil {
-		fmt.Printf("%v Error seeking file: %v", promptui.IconBad, err)
+	task, err := findTask(store, id)
+	if err != nil {
+		fmt.Printf("%v %v\n", promptui.IconBad, err)
 		return
 	}
 
-	// Write the updated records
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
-
-	if err := writer.WriteAll(newRecords); err != nil {
+	if err := trashTask(store, task); err != nil {
 		fmt.Printf("%v Error writing to CSV: %v", promptui.IconBad, err)
 		return
 	}
 
-	fmt.Printf("%v Task with ID %d deleted successfully.\n", promptui.IconGood, id)
+	fmt.Printf("%v Task with ID %d moved to the trash.\n", promptui.IconGood, task.ID)
 	fmt.Printf("%v CSV Data updated\n", promptui.IconGood)
-}
\ No newline at end of file
+}
+
+// trashTask marks the task as deleted without removing it from the store.
+func trashTask(store taskStore, task models.Task) error {
+	now := time.Now().UTC()
+	task.DeletedAt = &now
+	return store.Update(task)
+}
@@ -1,16 +1,17 @@
 package cmd
 
 import (
-	"encoding/csv"
 	"fmt"
 	"os"
+	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
-	"path/filepath"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+	"github.com/unf6/testing/pkg/utils"
 )
 
 var editCmd = &cobra.Command{
@@ -18,31 +19,47 @@ var editCmd = &cobra.Command{
 	Short: "Edit a task",
 	Long: "Edit a task by providing a title and id",
     Args: cobra.MaximumNArgs(3),
+	Annotations: writeCommand,
 	Run: func(cmd *cobra.Command, args []string) {
 	
-		id, _ := cmd.Flags().GetInt("id")
+		id, _ := cmd.Flags().GetString("id")
 		title, _ := cmd.Flags().GetString("title")
 		status, _ := cmd.Flags().GetString("status")
+		useEditor, _ := cmd.Flags().GetBool("editor")
+		var labels taskLabels
+		if cmd.Flags().Changed("project") {
+			project, _ := cmd.Flags().GetString("project")
+			labels.project = &project
+		}
+		if cmd.Flags().Changed("tags") {
+			tags, _ := cmd.Flags().GetStringSlice("tags")
+			labels.tags = &tags
+		}
 
 	
-		saveOptionPrompt := promptui.Select{
-			Label: "Where is to save the task",
-			Items: []string{"Database (sqlite)", "CSV File"},
-			CursorPos: 0,
-			
-		}
+		saveOption := configuredChoice()
+		if saveOption == "" {
+			saveOptionPrompt := promptui.Select{
+				Label: "Where is to save the task",
+				Items: []string{"Database (sqlite)", "CSV File"},
+				CursorPos: 0,
+			}
 
-		_, saveOption, saveOptionErr := saveOptionPrompt.Run()
-		if saveOptionErr != nil {
-			fmt.Printf("%s Error: %v", promptui.IconBad, saveOptionErr)
-			os.Exit(1)
+			var saveOptionErr error
+			_, saveOption, saveOptionErr = saveOptionPrompt.Run()
+			if saveOptionErr != nil {
+				fmt.Printf("%s Error: %v", promptui.IconBad, saveOptionErr)
+				os.Exit(1)
+			}
 		}
 
 		switch saveOption {
+		case "Remote":
+			editTaskInStore(getRemoteStore(), id, title, status, useEditor, labels)
 		case "CSV File":
-			editTaskInCSV(id, title, status)
+			editTaskInCSV(id, title, status, useEditor, labels)
 		default:
-			editTaskInDatabase(id, title, status)
+			editTaskInDatabase(id, title, status, useEditor, labels)
 		}
         fmt.Printf("%s Task edited succesfully!", promptui.IconGood)
 	},
@@ -52,15 +69,30 @@ var editCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(editCmd)
 
-	editCmd.Flags().Int("id", 0, "Id of the task")
+	editCmd.Flags().String("id", "", "Id of the task (integer ID or UID prefix)")
 	editCmd.Flags().String("title", "", "New title for the task")
 	editCmd.Flags().String("status", "", "New status for the task")
+	editCmd.Flags().BoolP("editor", "e", false, "Edit the description in the editor of the config file")
+	editCmd.Flags().String("project", "", "New project for the task, empty to remove it")
+	editCmd.Flags().StringSlice("tags", nil, "New comma separated tags for the task, empty to remove them")
 }
 
-func editTaskInDatabase(id int, title string, status string) {
-	db := database.GetDB()
+// taskLabels are the project and tags given to edit, nil when unchanged.
+type taskLabels struct {
+	project *string
+	tags    *[]string
+}
+
+func editTaskInDatabase(id string, title string, status string, useEditor bool, labels taskLabels) {
+	editTaskInStore(newSQLiteStore(), id, title, status, useEditor, labels)
+}
 
-	if id == 0 {
+func editTaskInCSV(id string, title string, status string, useEditor bool, labels taskLabels) {
+	editTaskInStore(newCSVStore(), id, title, status, useEditor, labels)
+}
+
+func editTaskInStore(store taskStore, id string, title string, status string, useEditor bool, labels taskLabels) {
+	if id == "" {
 		prompt := promptui.Prompt {
 			Label: "Task ID",
 			Validate: validateIDInput,
@@ -72,7 +104,14 @@ func editTaskInDatabase(id int, title string, status string) {
 			fmt.Printf("%s Error: %v\n", promptui.IconBad, err)
 			os.Exit(1)
 		}
-		id, _ = strconv.Atoi(idInput)
+		id = idInput
+	}
+
+	// Fetch the existing task
+	task, err := findTask(store, id)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
 	}
 
 	if title == "" {
@@ -91,110 +130,69 @@ func editTaskInDatabase(id int, title string, status string) {
 		_, status, _ = prompt.Run()
 	}
 
-	// Fetch the existing task
-	var currentTitle, currentStatus string
-	err := db.QueryRow("SELECT title, status FROM tasks WHERE id = ?", id).Scan(&currentTitle, &currentStatus)
-	if err != nil {
-		fmt.Printf("Task with ID %d not found: %v\n", id, err)
-		os.Exit(1)
+	if useEditor {
+		description, err := editInEditor(task.Description)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		task.Description = description
 	}
 
 	// If no new values provided, keep the existing ones
-	if title == "" {
-		title = currentTitle
+	if title != "" {
+		task.Title = title
 	}
-	if status == "" {
-		status = currentStatus
+	if status != "" {
+		task.Status = status
 	}
+	if labels.project != nil {
+		task.Project = strings.TrimSpace(*labels.project)
+	}
+	if labels.tags != nil {
+		task.Tags = tasks.NormalizeTags(*labels.tags)
+	}
+	task.UpdatedAt = time.Now().UTC()
 
-	// Update the task in the database
-	updateQuery := `
-		UPDATE tasks
-		SET title = ?, status = ?, updated_at = ?
-		WHERE id = ?
-	`
-	_, err = db.Exec(updateQuery, title, status, time.Now().UTC(), id)
-	if err != nil {
-		fmt.Printf("Failed to update the task: %v\n", err)
+	if err := store.Update(task); err != nil {
+		fmt.Printf("%v\n", err)
 		os.Exit(1)
 	}
 }
 
-func editTaskInCSV(id int, title string, status string) {
-
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
-
-	// Create the configuration directory if it doesn't exist
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err = os.MkdirAll(configDir, 0755)
-		if err != nil {
-			fmt.Printf("Failed to create config directory: %v\n", err)
-			os.Exit(1)
-		}
+// editInEditor opens text in the editor setting and returns it as saved.
+func editInEditor(text string) (string, error) {
+	args := strings.Fields(cfg.Editor)
+	if len(args) == 0 {
+		return text, fmt.Errorf("no editor set, set one with config set editor")
 	}
 
-	// Define the CSV file path
-	csvFilePath := filepath.Join(configDir, "tasks.csv")
-
-	// Open CSV file
-	file, err := os.OpenFile(csvFilePath, os.O_RDWR, 0644)
+	file, err := os.CreateTemp("", "tasks-cli-*.txt")
 	if err != nil {
-		fmt.Printf("Failed to open CSV file: %v\n", err)
-		os.Exit(1)
+		return text, fmt.Errorf("failed to create a temporary file: %v", err)
 	}
-	defer file.Close()
-
-	// Read all records
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		fmt.Printf("Failed to read CSV file: %v\n", err)
-		os.Exit(1)
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return text, fmt.Errorf("failed to write a temporary file: %v", err)
 	}
+	file.Close()
 
-	// Find and update the task
-	found := false
-	for i, record := range records {
-		if i == 0 {
-			// Skip header row
-			continue
-		}
-		recordID, _ := strconv.Atoi(record[0])
-		if recordID == id {
-			found = true
-			if title != "" {
-				record[1] = title
-			}
-			if status != "" {
-				record[3] = status
-			}
-			record[5] = time.Now().UTC().String() // Update updated_at timestamp
-			records[i] = record
-			break
-		}
+	editor := exec.Command(args[0], append(args[1:], file.Name())...)
+	editor.Stdin, editor.Stdout, editor.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := editor.Run(); err != nil {
+		return text, fmt.Errorf("editor %s failed: %v", args[0], err)
 	}
 
-	if !found {
-		fmt.Printf("Task with ID %d not found in CSV file.\n", id)
-		os.Exit(1)
-	}
-
-	// Write updated records back to the file
-	file.Truncate(0)
-	file.Seek(0, 0)
-	writer := csv.NewWriter(file)
-	err = writer.WriteAll(records)
+	edited, err := os.ReadFile(file.Name())
 	if err != nil {
-		fmt.Printf("Failed to update CSV file: %v\n", err)
-		os.Exit(1)
+		return text, fmt.Errorf("failed to read the edited description: %v", err)
 	}
-	writer.Flush()
+	return strings.TrimRight(string(edited), "\n"), nil
 }
 
-
 func validateIDInput(input string) error {
-	_, err := strconv.Atoi(input)
-	if err != nil {
+	if _, err := strconv.Atoi(input); err != nil && !utils.IsULIDPrefix(input) {
 		return fmt.Errorf("invalid id")
 	}
 	return nil
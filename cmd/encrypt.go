@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// keyring holds the keys of the encrypted stores, cached for the session
+// following the key_cache setting.
+var keyring = &encryption.Keyring{Passphrase: askPassphrase}
+
+// encryptCmd represents the encrypt command
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the tasks of the workspace with a passphrase",
+	Long: `Encrypt the CSV file of the workspace, and the titles, descriptions,
+projects and tags of the tasks in its database along with their history, undo
+journal and webhook payloads, with a passphrase. Commands reading the tasks ask for it, or read
+it from TASKS_CLI_PASSPHRASE, and remember it for the key_cache setting, 15
+minutes by default; lock forgets it. Exports of an encrypted workspace are
+encrypted too.
+
+The new passphrase is read from TASKS_CLI_NEW_PASSPHRASE when set. Statuses
+and dates are not encrypted. Webhooks are sent decrypted, the end of a command
+only sends them once the passphrase was given.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sqlite, csvStore := encryptableStores()
+		if dbEncrypted, csvEncrypted := storesEncrypted(sqlite, csvStore); dbEncrypted || csvEncrypted {
+			fmt.Printf("%s The workspace is already encrypted, change its passphrase with rekey\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		key := newPassphraseKey()
+		reencryptStores(sqlite, csvStore, key, true, true)
+		keyring.Add(key)
+
+		fmt.Printf("%s Encrypted the workspace\n", promptui.IconGood)
+		fmt.Printf("  Database: %s\n  CSV file: %s\n", workspace.DB, workspace.CSV)
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypt the tasks of the workspace for good",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sqlite, csvStore := encryptableStores()
+		dbEncrypted, csvEncrypted := storesEncrypted(sqlite, csvStore)
+		if !dbEncrypted && !csvEncrypted {
+			fmt.Printf("%s The workspace is not encrypted\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		reencryptStores(sqlite, csvStore, nil, dbEncrypted, csvEncrypted)
+		keyring.Forget()
+		fmt.Printf("%s Decrypted the workspace\n", promptui.IconGood)
+	},
+}
+
+var rekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Change the passphrase of the encrypted workspace",
+	Long: `Change the passphrase of the encrypted workspace: the database and CSV file are
+encrypted again with a key derived from the new passphrase, read from
+TASKS_CLI_NEW_PASSPHRASE when set. Exports keep the passphrase they were
+made with.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sqlite, csvStore := encryptableStores()
+		dbEncrypted, csvEncrypted := storesEncrypted(sqlite, csvStore)
+		if !dbEncrypted && !csvEncrypted {
+			fmt.Printf("%s The workspace is not encrypted, encrypt it with encrypt\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		// The current passphrase is checked before asking for the new one.
+		if dbEncrypted {
+			if _, err := sqlite.List(); err != nil {
+				fmt.Printf("%s %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+		}
+		if csvEncrypted {
+			if _, err := csvStore.List(); err != nil {
+				fmt.Printf("%s %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+		}
+
+		key := newPassphraseKey()
+		reencryptStores(sqlite, csvStore, key, dbEncrypted, csvEncrypted)
+		keyring.Forget()
+		keyring.Add(key)
+		fmt.Printf("%s Changed the passphrase of the workspace\n", promptui.IconGood)
+	},
+}
+
+var lockCmd = &cobra.Command{
+	Use:               "lock",
+	Short:             "Forget the cached keys of the encrypted workspaces",
+	Args:              cobra.NoArgs,
+	PersistentPreRun:  loadSettingsOnly,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := keyring.Forget(); err != nil {
+			fmt.Printf("%s Failed to forget the cached keys: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Forgot the cached keys, the passphrase will be asked again\n", promptui.IconGood)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(encryptCmd)
+	rootCmd.AddCommand(decryptCmd)
+	rootCmd.AddCommand(rekeyCmd)
+	rootCmd.AddCommand(lockCmd)
+}
+
+// configureKeyring sets the cache of the keyring from the key_cache setting.
+// Keys are not cached when no private runtime directory is available.
+func configureKeyring() {
+	keyring.TTL, _ = time.ParseDuration(cfg.KeyCache)
+	if dir, err := utils.GetRuntimeDir(); err == nil {
+		keyring.Dir = filepath.Join(dir, "keys")
+	}
+}
+
+// askPassphrase returns TASKS_CLI_PASSPHRASE, or asks for the passphrase.
+func askPassphrase() (string, error) {
+	if passphrase := os.Getenv("TASKS_CLI_PASSPHRASE"); passphrase != "" {
+		return passphrase, nil
+	}
+	prompt := promptui.Prompt{Label: "Passphrase", Mask: '*'}
+	return prompt.Run()
+}
+
+// newPassphraseKey derives a key with a new salt from TASKS_CLI_NEW_PASSPHRASE,
+// or from a new passphrase asked twice, exiting on failure.
+func newPassphraseKey() *encryption.Key {
+	passphrase := os.Getenv("TASKS_CLI_NEW_PASSPHRASE")
+	if passphrase == "" {
+		var err error
+		passphrase, err = (&promptui.Prompt{Label: "New passphrase", Mask: '*'}).Run()
+		if err != nil {
+			fmt.Printf("%s Passphrase input failed: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		repeated, err := (&promptui.Prompt{Label: "Repeat the new passphrase", Mask: '*'}).Run()
+		if err != nil {
+			fmt.Printf("%s Passphrase input failed: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if repeated != passphrase {
+			fmt.Printf("%s The passphrases do not match\n", promptui.IconBad)
+			os.Exit(1)
+		}
+	}
+
+	key, err := encryption.NewKey(passphrase)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	return key
+}
+
+// contentKey returns the key the database of the workspace is encrypted
+// with, nil when it is not encrypted.
+func contentKey() (*encryption.Key, error) {
+	return tasks.DatabaseKey(database.GetDB(), keyring.Key)
+}
+
+// encryptableStores returns the database and CSV file stores of the
+// workspace, whatever the backend setting.
+func encryptableStores() (*tasks.SQLiteStore, *tasks.CSVStore) {
+	return &tasks.SQLiteStore{DB: database.GetDB(), Keys: keyring.Key},
+		&tasks.CSVStore{Path: getCSVFilePath(), Keys: keyring.Key}
+}
+
+// reencryptStores encrypts the database and the CSV file of the workspace
+// with key, or decrypts them when key is nil, exiting on failure. The CSV
+// file is written beside the old one and only replaces it once the database
+// is committed, the database gets its previous key back when that fails, so
+// that both stay under the same key.
+func reencryptStores(sqlite *tasks.SQLiteStore, csvStore *tasks.CSVStore, key *encryption.Key, db bool, csv bool) {
+	var staged *tasks.StagedEncryption
+	if csv {
+		var err error
+		if staged, err = csvStore.StageEncrypt(key); err != nil {
+			fmt.Printf("%s Failed to encrypt the CSV file: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+	}
+
+	var old *encryption.Key
+	if db {
+		var err error
+		if old, err = contentKey(); err == nil {
+			err = sqlite.Encrypt(key)
+		}
+		if err != nil {
+			if staged != nil {
+				staged.Abort()
+			}
+			fmt.Printf("%s Failed to encrypt the database: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+	}
+
+	if staged != nil {
+		if err := staged.Commit(); err != nil {
+			fmt.Printf("%s Failed to encrypt the CSV file: %v\n", promptui.IconBad, err)
+			if db {
+				if err := sqlite.Encrypt(old); err != nil {
+					fmt.Printf("%s Failed to restore the previous key of the database, it is encrypted with the new passphrase: %v\n", promptui.IconBad, err)
+				}
+			}
+			os.Exit(1)
+		}
+	}
+}
+
+// storesEncrypted reports whether the database and the CSV file of the
+// workspace are encrypted, exiting when it cannot be told.
+func storesEncrypted(sqlite *tasks.SQLiteStore, csvStore *tasks.CSVStore) (bool, bool) {
+	dbEncrypted, err := tasks.DatabaseEncrypted(sqlite.DB)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	csvEncrypted, err := csvStore.Encrypted()
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	return dbEncrypted, csvEncrypted
+}
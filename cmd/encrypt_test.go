@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+func TestEncryptedHistoryReaders(t *testing.T) {
+	useTestWorkspace(t)
+	encryptTestWorkspace(t, "correct horse")
+
+	store := newSQLiteStore()
+	task, err := store.Add(models.Task{Title: "Call Acme", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	for _, status := range []string{"in-progress", "completed"} {
+		task.Status = status
+		if err := store.Update(task); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	var stored string
+	var completedAt time.Time
+	if err := database.GetDB().QueryRow(`SELECT new_value, changed_at FROM task_history WHERE field = 'status' ORDER BY id DESC LIMIT 1`).Scan(&stored, &completedAt); err != nil {
+		t.Fatal(err)
+	}
+	if !encryption.IsEncryptedString(stored) {
+		t.Fatalf("the history value %q is not encrypted", stored)
+	}
+
+	// stats and chart find the transitions instead of falling back to the
+	// last update.
+	flows, err := getTaskFlows(store)
+	if err != nil {
+		t.Fatalf("getTaskFlows: %v", err)
+	}
+	if len(flows) != 1 || flows[0].StartedAt == nil || flows[0].CompletedAt == nil || !flows[0].CompletedAt.Equal(completedAt) {
+		t.Errorf("flows = %+v, want the start and completion of the history", flows)
+	}
+
+	stream := &eventStream{store: store, service: tasks.NewService(store)}
+	events, err := stream.eventsAfter(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("eventsAfter: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want created and 2 updates", len(events))
+	}
+	if change := events[2].Changes["status"]; change.Old != "in-progress" || change.New != "completed" {
+		t.Errorf("status change = %+v, want in-progress to completed", change)
+	}
+	for _, event := range events {
+		for field, change := range event.Changes {
+			if strings.HasPrefix(change.Old, "enc1:") || strings.HasPrefix(change.New, "enc1:") {
+				t.Errorf("event %d streams the %s change encrypted: %+v", event.ID, field, change)
+			}
+		}
+		if event.Task == nil || event.Task.Title != "Call Acme" {
+			t.Errorf("event %d task = %+v, want the decrypted task", event.ID, event.Task)
+		}
+	}
+}
+
+func TestEncryptScrubsPlaintext(t *testing.T) {
+	useTestWorkspace(t)
+
+	store := newSQLiteStore()
+	task, err := store.Add(models.Task{Title: "Call Initech", Description: "About the Initech renewal", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	task.Title = "Call Initech again"
+	if err := store.Update(task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	encryptTestWorkspace(t, "correct horse")
+
+	// Neither the rows, their history and journal nor the freed pages hold
+	// the plaintext once encrypted.
+	for _, path := range []string{workspace.DB, workspace.DB + "-journal"} {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(data, []byte("Initech")) {
+			t.Errorf("%s holds the plaintext of the task once encrypted", filepath.Base(path))
+		}
+	}
+}
+
+func TestEncryptedWebhookPayloads(t *testing.T) {
+	useTestWorkspace(t)
+	receiver := newWebhookReceiver(t)
+	addTestWebhook(t, receiver.URL, "s3cret")
+	if _, err := newSQLiteStore().Add(models.Task{Title: "Call Acme", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	// Payloads queued before the encryption are encrypted with the rest.
+	encryptTestWorkspace(t, "correct horse")
+	if _, err := newSQLiteStore().Add(models.Task{Title: "Call Globex", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	rows, err := database.GetDB().Query(`SELECT payload FROM webhook_outbox`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var payload string
+		rows.Scan(&payload)
+		if !encryption.IsEncryptedString(payload) || strings.Contains(payload, "Call") {
+			t.Errorf("outbox payload %q is not encrypted", payload)
+		}
+	}
+	rows.Close()
+
+	// The end of a command does not ask for the passphrase to deliver them.
+	locked := &encryption.Keyring{Passphrase: func() (string, error) {
+		t.Error("the passphrase was asked")
+		return "", encryption.ErrLocked
+	}}
+	if _, _, err := deliverWebhooks(context.Background(), 0, nil, locked.Unlocked); !errors.Is(err, encryption.ErrLocked) {
+		t.Errorf("deliverWebhooks with a locked keyring: %v, want ErrLocked", err)
+	}
+	if requests := receiver.received(); len(requests) != 0 {
+		t.Fatalf("%d webhooks sent with a locked keyring", len(requests))
+	}
+
+	if delivered, _, err := deliverWebhooks(context.Background(), 0, nil, keyring.Key); err != nil || delivered != 2 {
+		t.Fatalf("deliverWebhooks = %d delivered, %v", delivered, err)
+	}
+	for i, request := range receiver.received() {
+		verifySignature(t, "s3cret", request)
+		var payload webhookPayload
+		if err := json.Unmarshal(request.body, &payload); err != nil || !strings.HasPrefix(payload.Task.Title, "Call ") {
+			t.Errorf("webhook %d payload = %s, %v; want the decrypted task", i, request.body, err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/api"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+const (
+	eventPollInterval = 500 * time.Millisecond
+	eventKeepAlive    = 15 * time.Second
+)
+
+// taskEvent is a change of a task as pushed to the event stream. It is built
+// from the history entries a change recorded, so changes made by other
+// processes are streamed as well.
+type taskEvent struct {
+	// ID is the last history entry of the change, clients resume after it
+	// with the Last-Event-ID header.
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+	// Action is the history action: create, update, trash, restore,
+	// archive, unarchive or delete.
+	Action  string                 `json:"action"`
+	TaskID  int                    `json:"task_id"`
+	TaskUID string                 `json:"task_uid"`
+	Actor   string                 `json:"actor"`
+	At      time.Time              `json:"at"`
+	Changes map[string]fieldChange `json:"changes,omitempty"`
+	// Task is the current state of the task, omitted once deleted for good.
+	Task *models.Task `json:"task,omitempty"`
+}
+
+type fieldChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// eventStream serves GET /api/events: it pushes task events as server-sent
+// events until the client disconnects. Without a Last-Event-ID only changes
+// from now on are sent.
+type eventStream struct {
+	store   taskStore
+	service *tasks.Service
+	mu      *sync.Mutex
+}
+
+func (s *eventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lastID, err := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+	if err != nil {
+		s.mu.Lock()
+		err = database.GetDB().QueryRow(`SELECT COALESCE(MAX(id), 0) FROM task_history`).Scan(&lastID)
+		s.mu.Unlock()
+		if err != nil {
+			api.WriteError(w, http.StatusInternalServerError, api.Error{Error: err.Error()})
+			return
+		}
+	}
+
+	controller := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(eventPollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case <-poll.C:
+			s.mu.Lock()
+			events, err := s.eventsAfter(r.Context(), lastID)
+			s.mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(w, "event: error\ndata: %q\n\n", err.Error())
+				controller.Flush()
+				return
+			}
+			for _, event := range events {
+				data, _ := json.Marshal(event)
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+				lastID = event.ID
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// eventsAfter groups the history entries of the store after lastID into one
+// event per change. Polls without new entries only query the history.
+func (s *eventStream) eventsAfter(ctx context.Context, lastID int64) ([]taskEvent, error) {
+	rows, err := database.GetDB().Query(`SELECT id, task_id, COALESCE(task_uid, ''), action, COALESCE(field, ''), COALESCE(old_value, ''), COALESCE(new_value, ''), COALESCE(actor, ''), changed_at
+		FROM task_history WHERE backend = ? AND id > ? ORDER BY id LIMIT 1000`, s.store.Key(), lastID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	var entries []historyEntry
+	for rows.Next() {
+		var id int64
+		var entry historyEntry
+		if err := rows.Scan(&id, &entry.TaskID, &entry.TaskUID, &entry.Action, &entry.Field, &entry.OldValue, &entry.NewValue, &entry.Actor, &entry.ChangedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		entries = append(entries, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	// The history values are encrypted along the tasks.
+	key, err := contentKey()
+	if err != nil {
+		return nil, err
+	}
+	var events []taskEvent
+	for i, entry := range entries {
+		if entry.OldValue, err = key.DecryptString(entry.OldValue, historyLocation("old_value", entry.TaskUID)); err != nil {
+			return nil, err
+		}
+		if entry.NewValue, err = key.DecryptString(entry.NewValue, historyLocation("new_value", entry.TaskUID)); err != nil {
+			return nil, err
+		}
+
+		// The entries of one change share their task and time.
+		last := len(events) - 1
+		if last < 0 || events[last].TaskID != entry.TaskID || !events[last].At.Equal(entry.ChangedAt) {
+			events = append(events, taskEvent{TaskID: entry.TaskID, TaskUID: entry.TaskUID, Actor: entry.Actor, At: entry.ChangedAt, Action: entry.Action})
+			last++
+		}
+		event := &events[last]
+		event.ID = ids[i]
+		if entry.Action == "update" {
+			if event.Changes == nil {
+				event.Changes = make(map[string]fieldChange)
+			}
+			event.Changes[entry.Field] = fieldChange{Old: entry.OldValue, New: entry.NewValue}
+		} else {
+			event.Action = entry.Action
+		}
+	}
+
+	uids := make([]string, len(events))
+	for i, event := range events {
+		uids[i] = event.TaskUID
+	}
+	current, err := s.service.ByUID(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		switch events[i].Action {
+		case "create":
+			events[i].Type = "created"
+		case "trash", "delete":
+			events[i].Type = "deleted"
+		default:
+			events[i].Type = "updated"
+		}
+		if task, ok := current[events[i].TaskUID]; ok {
+			events[i].Task = &task
+		}
+	}
+	return events, nil
+}
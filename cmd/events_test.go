@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+func TestEventsAfter(t *testing.T) {
+	useTestWorkspace(t)
+	store := newSQLiteStore()
+	stream := &eventStream{store: store, service: tasks.NewService(store), mu: &sync.Mutex{}}
+	ctx := context.Background()
+
+	if events, err := stream.eventsAfter(ctx, 0); err != nil || len(events) != 0 {
+		t.Fatalf("eventsAfter without history = %+v, %v", events, err)
+	}
+
+	task, err := store.Add(models.Task{Title: "Call Acme", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	task.Title, task.Status = "Call Acme back", "completed"
+	if err := store.Update(task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if _, err := store.Add(models.Task{Title: "Call Globex", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := store.Delete(2); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	events, err := stream.eventsAfter(ctx, 0)
+	if err != nil {
+		t.Fatalf("eventsAfter: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("eventsAfter = %+v, want 4 events", events)
+	}
+	if events[0].Type != "created" || events[0].Task == nil || events[0].Task.Title != "Call Acme back" {
+		t.Errorf("first event = %+v, want the creation with the current task", events[0])
+	}
+	if change := events[1].Changes["title"]; events[1].Type != "updated" || change.Old != "Call Acme" || change.New != "Call Acme back" || events[1].Changes["status"].New != "completed" {
+		t.Errorf("second event = %+v, want the title and status changes", events[1])
+	}
+	if events[3].Type != "deleted" || events[3].Task != nil {
+		t.Errorf("last event = %+v, want the deletion without the task", events[3])
+	}
+
+	if later, err := stream.eventsAfter(ctx, events[3].ID); err != nil || len(later) != 0 {
+		t.Errorf("eventsAfter the last event = %+v, %v, want none", later, err)
+	}
+}
@@ -2,18 +2,18 @@ package cmd
 
 import (
 	"strings"
-	"encoding/csv"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
-	"github.com/unf6/testing/pkg/database"
 	"github.com/unf6/testing/models"
-	"github.com/unf6/testing/pkg/utils"
+	"github.com/unf6/testing/pkg/encryption"
 )
 
 // Tasks represents the structure of a task.
@@ -24,24 +24,36 @@ var exportCmd = &cobra.Command{
 	Use:   "export [json|txt]",
 	Short: "Export tasks from SQLite or CSV file",
 	Long: `Export tasks to a specified file format (JSON or TXT).
-If no arguments are provided, you will be prompted to select the format interactively.`,
+If no arguments are provided, you will be prompted to select the format interactively.
+Exports of an encrypted workspace are encrypted with its passphrase unless
+--encrypt=false is given; --encrypt asks for a passphrase in other workspaces.
+import decrypts them.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Prompt for data source
-		sourcePrompt := promptui.Select{
-			Label: "Select data source",
-			Items: []string{"SQLite", "CSV"},
-		}
-		_, source, err := sourcePrompt.Run()
-		if err != nil {
-			fmt.Printf("Error during source selection: %v\n", err)
-			return
+		// Prompt for data source, unless the config file selects a backend
+		source := configuredChoice()
+		var err error
+		if source == "" {
+			sourcePrompt := promptui.Select{
+				Label: "Select data source",
+				Items: []string{"SQLite", "CSV"},
+			}
+			_, source, err = sourcePrompt.Run()
+			if err != nil {
+				fmt.Printf("Error during source selection: %v\n", err)
+				return
+			}
 		}
 
+		includeArchived, _ := cmd.Flags().GetBool("include-archived")
+
 		var tasks []models.Task
-		if source == "SQLite" {
-			tasks = fetchTasksFromSQLite()
-		} else {
-			tasks = fetchTasksFromCSV()
+		switch source {
+		case "Remote":
+			tasks = fetchTasksFromStore(getRemoteStore(), includeArchived)
+		case "SQLite", "Database (sqlite)":
+			tasks = fetchTasksFromSQLite(includeArchived)
+		default:
+			tasks = fetchTasksFromCSV(includeArchived)
 		}
 
 		if len(tasks) == 0 {
@@ -81,12 +93,14 @@ If no arguments are provided, you will be prompted to select the format interact
 			return
 		}
 
+		key := exportKey(cmd)
+
 		// Export tasks
 		switch format {
 		case "json":
-			exportToJSON(tasks, fileName+".json")
+			exportToJSON(tasks, fileName+".json", key)
 		case "txt":
-			exportToTXT(tasks, fileName+".txt")
+			exportToTXT(tasks, fileName+".txt", key)
 		default:
 			fmt.Println("Invalid format selected.")
 		}
@@ -94,117 +108,103 @@ If no arguments are provided, you will be prompted to select the format interact
 }
 
 // fetchTasksFromSQLite fetches tasks from the SQLite database
-func fetchTasksFromSQLite() []models.Task {
-	db := database.GetDB()
-	rows, err := db.Query("SELECT id, title, description, status, created_at, updated_at FROM tasks")
+func fetchTasksFromSQLite(includeArchived bool) []models.Task {
+	return fetchTasksFromStore(newSQLiteStore(), includeArchived)
+}
+
+// fetchTasksFromCSV fetches tasks from the CSV file
+func fetchTasksFromCSV(includeArchived bool) []models.Task {
+	return fetchTasksFromStore(newCSVStore(), includeArchived)
+}
+
+func fetchTasksFromStore(store taskStore, includeArchived bool) []models.Task {
+	tasks, err := store.List()
 	if err != nil {
-		fmt.Printf("Error querying SQLite database: %v\n", err)
+		fmt.Printf("%v\n", err)
 		return nil
 	}
-	defer rows.Close()
 
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		var createdAt, updatedAt string
-		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Status, &createdAt, &updatedAt)
+	if includeArchived {
+		archived, err := store.Archived()
 		if err != nil {
-			fmt.Printf("Error scanning SQLite row: %v\n", err)
+			fmt.Printf("%v\n", err)
 			return nil
 		}
-
-		task.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-		task.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-		tasks = append(tasks, task)
+		tasks = append(tasks, archived...)
+		sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
 	}
 	return tasks
 }
 
-// fetchTasksFromCSV fetches tasks from the CSV file
-func fetchTasksFromCSV() []models.Task {
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
-	csvFilePath := filepath.Join(configDir, "tasks.csv")
-
-	file, err := os.Open(csvFilePath)
+// exportKey returns the key exports are encrypted with following --encrypt,
+// nil when they are not: the key of the workspace when it is encrypted, or a
+// key derived from a new passphrase.
+func exportKey(cmd *cobra.Command) *encryption.Key {
+	key, err := contentKey()
 	if err != nil {
-		fmt.Printf("Error opening CSV file: %v\n", err)
-		return nil
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
-	if err != nil {
-		fmt.Printf("Error reading CSV file: %v\n", err)
+	encrypt, _ := cmd.Flags().GetBool("encrypt")
+	switch {
+	case !cmd.Flags().Changed("encrypt"):
+		return key
+	case !encrypt:
 		return nil
+	case key == nil:
+		return newPassphraseKey()
 	}
-
-	var tasks []models.Task
-	for i, record := range records {
-		if i == 0 {
-			// Skip header row
-			continue
-		}
-
-		createdAt, _ := time.Parse(time.RFC3339, record[4])
-		updatedAt, _ := time.Parse(time.RFC3339, record[5])
-
-		tasks = append(tasks, models.Task{
-			ID:          utils.MustAtoi(record[0]),
-			Title:       record[1],
-			Description: record[2],
-			Status:      record[3],
-			CreatedAt:   createdAt,
-			UpdatedAt:   updatedAt,
-		})
-	}
-	return tasks
+	return key
 }
 
 // exportToJSON exports tasks to a JSON file
-func exportToJSON(tasks []models.Task, fileName string) {
-	filePath := filepath.Join(".", fileName)
-	file, err := os.Create(filePath)
-	if err != nil {
-		fmt.Printf("Error creating file: %v\n", err)
-		return
-	}
-	defer file.Close()
-
-	encoder := json.NewEncoder(file)
+func exportToJSON(tasks []models.Task, fileName string, key *encryption.Key) {
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(tasks); err != nil {
 		fmt.Printf("Error writing to JSON file: %v\n", err)
 		return
 	}
 
-	fmt.Printf("Tasks exported successfully to %s\n", filePath)
+	writeExport(fileName, data.Bytes(), key)
 }
 
 // exportToTXT exports tasks to a TXT file
-func exportToTXT(tasks []models.Task, fileName string) {
+func exportToTXT(tasks []models.Task, fileName string, key *encryption.Key) {
+	var data bytes.Buffer
+	for _, task := range tasks {
+		fmt.Fprintf(&data, "ID: %d\nUID: %s\nTitle: %s\nDescription: %s\nStatus: %s\nProject: %s\nTags: %s\nCreatedAt: %s\nUpdatedAt: %s\n\n",
+			task.ID, task.UID, task.Title, task.Description, task.Status, task.Project, strings.Join(task.Tags, ", "),
+			task.CreatedAt.Format(time.RFC3339), task.UpdatedAt.Format(time.RFC3339))
+	}
+
+	writeExport(fileName, data.Bytes(), key)
+}
+
+// writeExport writes an export file in the current directory, encrypted with
+// key unless it is nil.
+func writeExport(fileName string, data []byte, key *encryption.Key) {
 	filePath := filepath.Join(".", fileName)
-	file, err := os.Create(filePath)
-	if err != nil {
-		fmt.Printf("Error creating file: %v\n", err)
+	mode := os.FileMode(0644)
+	if key != nil {
+		data, mode = key.Encrypt(data), 0600
+	}
+	if err := os.WriteFile(filePath, data, mode); err != nil {
+		fmt.Printf("Error writing to file: %v\n", err)
 		return
 	}
-	defer file.Close()
 
-	for _, task := range tasks {
-		line := fmt.Sprintf("ID: %d\nTitle: %s\nDescription: %s\nStatus: %s\nCreatedAt: %s\nUpdatedAt: %s\n\n",
-			task.ID, task.Title, task.Description, task.Status,
-			task.CreatedAt.Format(time.RFC3339), task.UpdatedAt.Format(time.RFC3339))
-		_, err := file.WriteString(line)
-		if err != nil {
-			fmt.Printf("Error writing to TXT file: %v\n", err)
-			return
-		}
+	if key != nil {
+		fmt.Printf("Tasks exported and encrypted successfully to %s\n", filePath)
+		return
 	}
-
 	fmt.Printf("Tasks exported successfully to %s\n", filePath)
 }
 
 func init() {
+	exportCmd.Flags().Bool("include-archived", false, "Also export archived tasks")
+	exportCmd.Flags().Bool("encrypt", false, "Encrypt the export with a passphrase (default is true in encrypted workspaces)")
 	rootCmd.AddCommand(exportCmd)
 }
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// historyEntry is one recorded change of a task.
+type historyEntry struct {
+	Backend   string    `json:"backend"`
+	TaskID    int       `json:"task_id"`
+	TaskUID   string    `json:"task_uid"`
+	Action    string    `json:"action"`
+	Field     string    `json:"field,omitempty"`
+	OldValue  string    `json:"old_value,omitempty"`
+	NewValue  string    `json:"new_value,omitempty"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:    "history <id>",
+	Short:  "Show the change history of a task",
+	Long:   `Show every recorded create, update and delete of a task, by its ID or UID prefix.`,
+	Args:   cobra.ExactArgs(1),
+	PreRun: requireLocalBackend,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := outputFormat(cmd)
+
+		store := promptStore("Which database should we show the history from?")
+
+		entries, err := getTaskHistory(store, args[0])
+		if err != nil {
+			fmt.Printf("%s Failed to fetch the task history: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No history found for task %s.\n", args[0])
+			return
+		}
+		printHistory(entries, format, false)
+	},
+}
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:    "log",
+	Short:  "Show recent changes across all tasks",
+	PreRun: requireLocalBackend,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := outputFormat(cmd)
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		entries, err := getRecentHistory(limit)
+		if err != nil {
+			fmt.Printf("%s Failed to fetch recent changes: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No changes recorded yet.")
+			return
+		}
+		printHistory(entries, format, true)
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringP("format", "f", "table", "Output format: table, json")
+	logCmd.Flags().StringP("format", "f", "table", "Output format: table, json")
+	logCmd.Flags().IntP("limit", "n", 20, "Number of changes to show")
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(logCmd)
+}
+
+// operationActor overrides the author of changes, the server sets it to the
+// API token making the request.
+var operationActor string
+
+// currentActor returns the name recorded as the author of changes.
+func currentActor() string {
+	if operationActor != "" {
+		return operationActor
+	}
+	if actor := os.Getenv("TASKS_CLI_ACTOR"); actor != "" {
+		return actor
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// recordHistory stores history entries, filling in the actor and time. The
+// values are encrypted when the database is.
+func recordHistory(entries ...historyEntry) error {
+	db := database.GetDB()
+	actor := currentActor()
+	now := time.Now().UTC()
+	key, err := contentKey()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		_, err := db.Exec(`INSERT INTO task_history (backend, task_id, task_uid, action, field, old_value, new_value, actor, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			entry.Backend, entry.TaskID, entry.TaskUID, entry.Action, entry.Field, key.EncryptString(entry.OldValue, historyLocation("old_value", entry.TaskUID)),
+			key.EncryptString(entry.NewValue, historyLocation("new_value", entry.TaskUID)), actor, now)
+		if err != nil {
+			return fmt.Errorf("error recording task history: %v", err)
+		}
+	}
+	return nil
+}
+
+// historyLocation returns the location of column in the history entries of
+// the task uid.
+func historyLocation(column string, uid string) encryption.Location {
+	return encryption.Location{Table: "task_history", Column: column, Row: uid}
+}
+
+// createdHistory describes the creation of a task.
+func createdHistory(backend string, task models.Task) historyEntry {
+	return historyEntry{Backend: backend, TaskID: task.ID, TaskUID: task.UID, Action: "create", NewValue: task.Title}
+}
+
+// deletedHistory describes the deletion of a task.
+func deletedHistory(backend string, task models.Task) historyEntry {
+	return historyEntry{Backend: backend, TaskID: task.ID, TaskUID: task.UID, Action: "delete", OldValue: task.Title}
+}
+
+// updatedHistory returns one entry per field that differs between old and updated.
+func updatedHistory(backend string, old models.Task, updated models.Task) []historyEntry {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"title", old.Title, updated.Title},
+		{"description", old.Description, updated.Description},
+		{"status", old.Status, updated.Status},
+		{"project", old.Project, updated.Project},
+		{"tags", strings.Join(old.Tags, ","), strings.Join(updated.Tags, ",")},
+	}
+
+	var entries []historyEntry
+	switch {
+	case old.DeletedAt == nil && updated.DeletedAt != nil:
+		entries = append(entries, historyEntry{Backend: backend, TaskID: old.ID, TaskUID: old.UID, Action: "trash", OldValue: old.Title})
+	case old.DeletedAt != nil && updated.DeletedAt == nil:
+		entries = append(entries, historyEntry{Backend: backend, TaskID: old.ID, TaskUID: old.UID, Action: "restore", NewValue: updated.Title})
+	}
+	switch {
+	case old.ArchivedAt == nil && updated.ArchivedAt != nil:
+		entries = append(entries, historyEntry{Backend: backend, TaskID: old.ID, TaskUID: old.UID, Action: "archive", OldValue: old.Title})
+	case old.ArchivedAt != nil && updated.ArchivedAt == nil:
+		entries = append(entries, historyEntry{Backend: backend, TaskID: old.ID, TaskUID: old.UID, Action: "unarchive", NewValue: updated.Title})
+	}
+
+	for _, field := range fields {
+		if field.old == field.new {
+			continue
+		}
+		entries = append(entries, historyEntry{
+			Backend:  backend,
+			TaskID:   old.ID,
+			TaskUID:  old.UID,
+			Action:   "update",
+			Field:    field.name,
+			OldValue: field.old,
+			NewValue: field.new,
+		})
+	}
+	return entries
+}
+
+// getTaskHistory returns the history of a task of the store by integer ID
+// or UID prefix, which keeps working once the task has been deleted. An ID
+// stands for the task holding it, not for the older tasks that had it.
+func getTaskHistory(store taskStore, ref string) ([]historyEntry, error) {
+	query := `SELECT backend, task_id, COALESCE(task_uid, ''), action, COALESCE(field, ''), COALESCE(old_value, ''), COALESCE(new_value, ''), COALESCE(actor, ''), changed_at
+		FROM task_history WHERE backend = ? AND `
+
+	var arg interface{}
+	if id, err := strconv.Atoi(ref); err == nil {
+		uid, err := historyUID(store, id)
+		if err != nil {
+			return nil, err
+		}
+		if uid == "" {
+			query += `task_id = ?`
+			arg = id
+		} else {
+			query += `task_uid = ?`
+			arg = uid
+		}
+	} else if utils.IsULIDPrefix(ref) {
+		query += `task_uid LIKE ? || '%'`
+		arg = ref
+	} else {
+		return nil, fmt.Errorf("invalid id %q", ref)
+	}
+
+	return queryHistory(query+` ORDER BY id`, store.Key(), arg)
+}
+
+// historyUID returns the UID of the task with the ID, or of the last task
+// recorded with it once deleted. It is empty for history recorded before
+// tasks had UIDs.
+func historyUID(store taskStore, id int) (string, error) {
+	page, err := tasks.NewService(store).List(context.Background(), tasks.ListOptions{View: tasks.ViewAll})
+	if err != nil {
+		return "", err
+	}
+	for _, task := range page.Tasks {
+		if task.ID == id {
+			return task.UID, nil
+		}
+	}
+
+	var uid string
+	err = database.GetDB().QueryRow(`SELECT COALESCE(task_uid, '') FROM task_history WHERE backend = ? AND task_id = ? ORDER BY id DESC LIMIT 1`,
+		store.Key(), id).Scan(&uid)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return uid, err
+}
+
+// getRecentHistory returns the latest changes of all backends, newest first.
+func getRecentHistory(limit int) ([]historyEntry, error) {
+	return queryHistory(`SELECT backend, task_id, COALESCE(task_uid, ''), action, COALESCE(field, ''), COALESCE(old_value, ''), COALESCE(new_value, ''), COALESCE(actor, ''), changed_at
+		FROM task_history ORDER BY id DESC LIMIT ?`, limit)
+}
+
+func queryHistory(query string, args ...interface{}) ([]historyEntry, error) {
+	key, err := contentKey()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.GetDB().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]historyEntry, 0)
+	for rows.Next() {
+		var entry historyEntry
+		if err := rows.Scan(&entry.Backend, &entry.TaskID, &entry.TaskUID, &entry.Action, &entry.Field,
+			&entry.OldValue, &entry.NewValue, &entry.Actor, &entry.ChangedAt); err != nil {
+			return nil, err
+		}
+		if entry.OldValue, err = key.DecryptString(entry.OldValue, historyLocation("old_value", entry.TaskUID)); err != nil {
+			return nil, err
+		}
+		if entry.NewValue, err = key.DecryptString(entry.NewValue, historyLocation("new_value", entry.TaskUID)); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func printHistory(entries []historyEntry, format string, withTask bool) {
+	if format == "json" {
+		jsonData, err := json.MarshalIndent(entries, "", " ")
+		if err != nil {
+			fmt.Printf("Failed to change data to JSON: %v", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonData))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	if withTask {
+		fmt.Fprint(w, "BACKEND\tTASK\t")
+	}
+	fmt.Fprintln(w, "WHEN\tACTION\tFIELD\tOLD VALUE\tNEW VALUE\tACTOR")
+	for _, entry := range entries {
+		if withTask {
+			fmt.Fprintf(w, "%s\t%d\t", entry.Backend, entry.TaskID)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ChangedAt.Local().Format("2006-01-02 15:04:05"),
+			entry.Action,
+			entry.Field,
+			entry.OldValue,
+			entry.NewValue,
+			entry.Actor,
+		)
+	}
+	w.Flush()
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unf6/testing/models"
+)
+
+func TestGetTaskHistoryByID(t *testing.T) {
+	useTestWorkspace(t)
+	store, _ := getStore("csv")
+
+	if _, err := store.Add(models.Task{Title: "Call Acme", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	deleted, err := store.Add(models.Task{Title: "Invoice", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := store.Delete(deleted.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	// The CSV file gives the ID of the deleted task to the next one.
+	reused, err := store.Add(models.Task{Title: "Write report", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if reused.ID != deleted.ID {
+		t.Fatalf("new task got ID %d, want the deleted ID %d", reused.ID, deleted.ID)
+	}
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{ref: "1", want: "create Call Acme"},
+		{ref: "2", want: "create Write report"},
+		{ref: deleted.UID, want: "create Invoice,delete Invoice"},
+		{ref: "3", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			entries, err := getTaskHistory(store, tt.ref)
+			if err != nil {
+				t.Fatalf("getTaskHistory: %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, strings.TrimSpace(entry.Action+" "+entry.NewValue+entry.OldValue))
+			}
+			if strings.Join(got, ",") != tt.want {
+				t.Errorf("history of %s = %q, want %q", tt.ref, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,17 +1,19 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"github.com/unf6/testing/models" // Import the models package
-	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
 	"github.com/unf6/testing/pkg/utils"
 )
 
@@ -20,6 +22,7 @@ var importCmd = &cobra.Command{
     Use:   "import [json|csv]",
     Short: "Import tasks into SQLite from CSV or JSON file",
     Long:  `Import tasks into SQLite from a CSV or JSON file. You can select the file format interactively if no arguments are provided.`,
+    Annotations: writeCommand,
     Run: func(cmd *cobra.Command, args []string) {
         // Determine import format
         var format string
@@ -34,18 +37,18 @@ var importCmd = &cobra.Command{
                 Label: "Select import format",
                 Items: []string{"JSON", "CSV"},
             }
-            _, format, err := formatPrompt.Run()
+            _, selected, err := formatPrompt.Run()
             if err != nil {
                 fmt.Printf("Error during format selection: %v\n", err)
                 return
             }
-            format = format
+            format = strings.ToLower(selected)
         }
 
         // Prompt for file path
         filePrompt := promptui.Prompt{
             Label:   "Enter import file path",
-            Default: fmt.Sprintf("%s/tasks.%s", filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli"), format),
+            Default: filepath.Join(utils.GetDataDir(), "tasks."+format),
         }
         filePath, err := filePrompt.Run()
         if err != nil {
@@ -74,38 +77,34 @@ var importCmd = &cobra.Command{
 
 // importFromJSON imports tasks from a JSON file into SQLite
 func importFromJSON(filePath string) error {
-    file, err := os.Open(filePath)
+    data, err := readImportFile(filePath)
     if err != nil {
         return fmt.Errorf("error opening JSON file: %v", err)
     }
-    defer file.Close()
 
     var tasks []models.Task
-    decoder := json.NewDecoder(file)
-    err = decoder.Decode(&tasks)
+    err = json.Unmarshal(data, &tasks)
     if err != nil {
         return fmt.Errorf("error decoding JSON file: %v", err)
     }
 
-    db := database.GetDB()
+    store := importTarget()
+    ids, uids, err := existingTaskKeys(store)
+    if err != nil {
+        return fmt.Errorf("error checking if task exists: %v", err)
+    }
     for _, task := range tasks {
-        // Check if task with the same ID already exists
-        var exists bool
-        err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM tasks WHERE id = ?)`, task.ID).Scan(&exists)
-        if err != nil {
-            return fmt.Errorf("error checking if task exists: %v", err)
-        }
-
-        if exists {
+        // Check if task with the same ID or UID already exists
+        if ids[task.ID] || uids[task.UID] {
             fmt.Printf("Task with ID %d already exists, skipping import...\n", task.ID)
             continue // Skip this task if it already exists
         }
 
-        _, err = db.Exec(`INSERT INTO tasks (id, title, description, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
-            task.ID, task.Title, task.Description, task.Status, task.CreatedAt, task.UpdatedAt)
+        added, err := store.Add(task)
         if err != nil {
-            return fmt.Errorf("error inserting task into database: %v", err)
+            return err
         }
+        ids[added.ID], uids[added.UID] = true, true
     }
 
     return nil
@@ -113,49 +112,104 @@ func importFromJSON(filePath string) error {
 
 // importFromCSV imports tasks from a CSV file into SQLite
 func importFromCSV(filePath string) error {
-    file, err := os.Open(filePath)
+    data, err := readImportFile(filePath)
     if err != nil {
         return fmt.Errorf("error opening CSV file: %v", err)
     }
-    defer file.Close()
 
-    reader := csv.NewReader(file)
+    reader := csv.NewReader(bytes.NewReader(data))
+    reader.FieldsPerRecord = -1
     records, err := reader.ReadAll()
     if err != nil {
         return fmt.Errorf("error reading CSV file: %v", err)
     }
 
-    db := database.GetDB()
+    store := importTarget()
+    ids, uids, err := existingTaskKeys(store)
+    if err != nil {
+        return fmt.Errorf("error checking if task exists: %v", err)
+    }
     for i, record := range records {
         if i == 0 {
             // Skip header row
             continue
         }
 
-        createdAt, _ := time.Parse(time.RFC3339, record[4])
-        updatedAt, _ := time.Parse(time.RFC3339, record[5])
+        createdAt, _ := tasks.ParseCSVTime(record[4])
+        updatedAt, _ := tasks.ParseCSVTime(record[5])
 
-        // Check if task with the same ID already exists
-        var exists bool
-        err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM tasks WHERE id = ?)`, utils.MustAtoi(record[0])).Scan(&exists)
-        if err != nil {
-            return fmt.Errorf("error checking if task exists: %v", err)
+        uid := utils.NewULID(createdAt)
+        if len(record) > 6 && record[6] != "" {
+            uid = record[6]
         }
 
-        if exists {
+        // Check if task with the same ID or UID already exists
+        if ids[utils.MustAtoi(record[0])] || uids[uid] {
             fmt.Printf("Task with ID %d already exists, skipping import...\n", utils.MustAtoi(record[0]))
             continue // Skip this task if it already exists
         }
 
-        _, err = db.Exec(`INSERT INTO tasks (id, title, description, status, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
-            utils.MustAtoi(record[0]), record[1], record[2], record[3], createdAt, updatedAt)
+        task := models.Task{
+            ID:          utils.MustAtoi(record[0]),
+            UID:         uid,
+            Title:       record[1],
+            Description: record[2],
+            Status:      record[3],
+            CreatedAt:   createdAt,
+            UpdatedAt:   updatedAt,
+        }
+        if len(record) > 10 {
+            task.Project = record[9]
+            task.Tags = tasks.NormalizeTags([]string{record[10]})
+        }
+        added, err := store.Add(task)
         if err != nil {
-            return fmt.Errorf("error inserting task into database: %v", err)
+            return err
         }
+        ids[added.ID], uids[added.UID] = true, true
     }
 
     return nil
 }
+
+// importTarget returns the store tasks are imported into: the backend the
+// config file selects, the database otherwise.
+func importTarget() taskStore {
+    if store := configuredStore(); store != nil {
+        return store
+    }
+    return newSQLiteStore()
+}
+
+// existingTaskKeys returns the IDs and UIDs taken in the store, trashed and
+// archived tasks included.
+func existingTaskKeys(store taskStore) (map[int]bool, map[string]bool, error) {
+    ids := make(map[int]bool)
+    uids := make(map[string]bool)
+    for _, list := range []func() ([]models.Task, error){store.List, store.Archived, store.Trashed} {
+        tasks, err := list()
+        if err != nil {
+            return nil, nil, err
+        }
+        for _, task := range tasks {
+            ids[task.ID] = true
+            uids[task.UID] = true
+        }
+    }
+    return ids, uids, nil
+}
+
+// readImportFile returns the content of an import file, decrypted when it is
+// an encrypted export.
+func readImportFile(filePath string) ([]byte, error) {
+    data, err := os.ReadFile(filePath)
+    if err != nil || !encryption.IsEncrypted(data) {
+        return data, err
+    }
+    data, _, err = encryption.Decrypt(data, keyring.Key)
+    return data, err
+}
+
 func init() {
     rootCmd.AddCommand(importCmd)
 }
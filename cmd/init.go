@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/config"
+	"github.com/unf6/testing/pkg/database"
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init [directory]",
+	Short: "Create a task store for the repository in a directory",
+	Long: `Create a .tasks directory holding the tasks of the repository in a directory,
+the current one by default. Commands run in the directory or below it use it
+instead of the workspace, the way git finds .git; a .tasks.db file is found the
+same way. Select a workspace with --workspace to use it there anyway.`,
+	Args:              cobra.MaximumNArgs(1),
+	PersistentPreRun:  loadSettingsOnly,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			fmt.Printf("%s Invalid directory: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		if existing, ok := config.Discover(dir); ok && existing.Name == dir {
+			fmt.Printf("%s %s already has a task store: %s\n", promptui.IconBad, dir, existing.DB)
+			os.Exit(1)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, ".tasks"), 0755); err != nil {
+			fmt.Printf("%s Failed to create the task store: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		local, _ := config.Discover(dir)
+		db, err := database.Open(local.DB)
+		if err != nil {
+			fmt.Printf("%s Failed to create the task store: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		db.Close()
+
+		fmt.Printf("%s Created the task store of %s\n", promptui.IconGood, dir)
+		fmt.Printf("  Database: %s\n  CSV file: %s\n", local.DB, local.CSV)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+}
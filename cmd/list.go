@@ -5,21 +5,20 @@ package cmd
 
 import (
 	"database/sql"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
-	"path/filepath"
-
 	"github.com/manifoldco/promptui"
 	"github.com/mergestat/timediff"
 	"github.com/spf13/cobra"
-	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/config"
 )
 
 // listCmd represents the list command
@@ -29,98 +28,107 @@ var listCmd = &cobra.Command{
 	Long:  `Display all tasks with their titles, descriptions, and status`,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		prompt := promptui.Select{
-			Label:     "Which database should we list the data from?",
-			Items:     []string{"Database (sqlite)", "CSV File"},
-			CursorPos: 0,
-		}
+		listChoice := configuredChoice()
+		if listChoice == "" {
+			prompt := promptui.Select{
+				Label:     "Which database should we list the data from?",
+				Items:     []string{"Database (sqlite)", "CSV File"},
+				CursorPos: 0,
+			}
 
-		_, listChoice, promptErr := prompt.Run()
+			var promptErr error
+			_, listChoice, promptErr = prompt.Run()
 
-		if promptErr != nil {
-			fmt.Printf("%s Error: %v\n", promptui.IconBad, promptErr)
-			os.Exit(1)
+			if promptErr != nil {
+				fmt.Printf("%s Error: %v\n", promptui.IconBad, promptErr)
+				os.Exit(1)
+			}
 		}
 
-		format, formatErr := cmd.Flags().GetString("format")
+		format := outputFormat(cmd)
+		archived, _ := cmd.Flags().GetBool("archived")
+
+		if value, _ := cmd.Flags().GetString("columns"); value != "" {
+			var settings config.Settings
+			if err := settings.Set("columns", value); err != nil {
+				fmt.Printf("%s Invalid columns: %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+			cfg.Columns = settings.Columns
+		}
 
-		if formatErr != nil {
-			fmt.Printf("Failed to get format flag: %v", formatErr)
-			os.Exit(1)
+		if all, _ := cmd.Flags().GetBool("all-workspaces"); all {
+			listAllWorkspaces(listChoice, format, archived)
+			return
 		}
 
 		switch listChoice {
+		case "Remote":
+			listFromStore(getRemoteStore(), format, archived)
 		case "CSV File":
-			listFromCSVFile(format)
+			listFromCSVFile(format, archived)
 		default:
-			listFromDatabase(format)
+			listFromDatabase(format, archived)
 		}
 	},
 }
 
 func init() {
 	listCmd.Flags().StringP("format", "f", "table", "Output format: table, json")
+	listCmd.Flags().Bool("archived", false, "List archived tasks instead")
+	listCmd.Flags().Bool("all-workspaces", false, "List the tasks of every workspace, with a workspace column")
+	listCmd.Flags().String("columns", "", "Comma separated columns of the table, e.g. id,title,status (default from the columns setting)")
 	rootCmd.AddCommand(listCmd)
 }
 
-func listFromDatabase(format string) {
-	// DB Connect
-	db := database.GetDB()
+func listFromDatabase(format string, archived bool) {
+	listFromStore(newSQLiteStore(), format, archived)
+}
 
-	listAllQuery := `
-		SELECT * FROM tasks;
-	`
-	rows, listAllErr := db.Query(listAllQuery)
+func listFromCSVFile(format string, archived bool) {
+	listFromStore(newCSVStore(), format, archived)
+}
 
-	if listAllErr != nil {
-		fmt.Printf("Failed to fetch all tasks: %v", listAllErr)
+func listFromStore(store taskStore, format string, archived bool) {
+	data, err := taskRows(store, trackingDB(), archived)
+	if err != nil {
+		fmt.Printf("%v", err)
 		os.Exit(1)
 	}
-	defer rows.Close()
-
-	data := getRowData(rows)
-	switch format {
-	case "json":
-		formatInJSON(data)
-	default:
-		formatInTable(data)
-	}
+	printTasks(data, format)
 }
 
-func listFromCSVFile(format string) {
-
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
-
-	// Create the configuration directory if it doesn't exist
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err = os.MkdirAll(configDir, 0755)
-		if err != nil {
-			fmt.Printf("Failed to create config directory: %v\n", err)
-			os.Exit(1)
-		}
+// taskRows returns the display data of the active or archived tasks of the
+// store, with the time tracked on them in db when it is not nil.
+func taskRows(store taskStore, db *sql.DB, archived bool) ([]DBTask, error) {
+	list := store.List
+	if archived {
+		list = store.Archived
 	}
 
-	// Define the CSV file path
-	csvFilePath := filepath.Join(configDir, "tasks.csv")
-
-	// Open CSV File
-	file, fileErr := os.OpenFile(csvFilePath, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0644)
-
-	if fileErr != nil {
-		fmt.Printf("Failed to open CSV File: %v", fileErr)
+	tasks, err := list()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch all tasks: %v", err)
 	}
-	defer file.Close()
 
-	// Read CSV File
-	reader := csv.NewReader(file)
-	records, errRecords := reader.ReadAll()
+	data := getDisplayData(tasks)
+	if db == nil {
+		return data, nil
+	}
 
-	if errRecords != nil {
-		fmt.Printf("Error loading CSV File records: %v", errRecords)
-		os.Exit(1)
+	totals, err := getTrackedTotals(db, store.Key())
+	if err != nil {
+		return nil, fmt.Errorf("Failed to fetch tracked time: %v", err)
+	}
+	for i, task := range tasks {
+		if total, ok := totals[task.UID]; ok {
+			data[i].Tracked = formatTracked(total)
+		}
 	}
+	return data, nil
+}
 
-	data := getDataFromCSVFile(records)
+func printTasks(data []DBTask, format string) {
 	switch format {
 	case "json":
 		formatInJSON(data)
@@ -129,28 +137,56 @@ func listFromCSVFile(format string) {
 	}
 }
 
+// tableColumn is a column of the task table.
+type tableColumn struct {
+	header string
+	// width is the length of the separator under the header.
+	width int
+	value func(DBTask) string
+}
+
+// tableColumns are the columns of the task table by their name in the
+// columns setting.
+var tableColumns = map[string]tableColumn{
+	"workspace":   {"WORKSPACE", 9, func(task DBTask) string { return task.Workspace }},
+	"id":          {"ID", 3, func(task DBTask) string { return strconv.Itoa(task.ID) }},
+	"uid":         {"UID", 26, func(task DBTask) string { return task.UID }},
+	"title":       {"TITLE", 20, func(task DBTask) string { return task.Title }},
+	"description": {"DESCRIPTION", 30, func(task DBTask) string { return task.Description }},
+	"status":      {"STATUS", 19, func(task DBTask) string { return task.Status }},
+	"project":     {"PROJECT", 12, func(task DBTask) string { return task.Project }},
+	"tags":        {"TAGS", 12, func(task DBTask) string { return task.Tags }},
+	"tracked":     {"TRACKED", 7, func(task DBTask) string { return task.Tracked }},
+	"created_at":  {"CREATED AT", 12, func(task DBTask) string { return task.CreatedAt }},
+	"updated_at":  {"UPDATED AT", 12, func(task DBTask) string { return task.UpdatedAt }},
+}
+
 func formatInTable(data []DBTask) {
-	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
-	// Headers
-	fmt.Fprintln(w, "ID\tTITLE\tDESCRIPTION\tSTATUS\tCREATED AT\tUPDATED AT")
+	columns := cfg.Columns
+	if len(columns) == 0 {
+		columns = config.Columns
+	}
+	if len(data) > 0 && data[0].Workspace != "" && !slices.Contains(columns, "workspace") {
+		columns = append([]string{"workspace"}, columns...)
+	}
 
-	// Separator line using dashes, adjusted to match column widths
-	fmt.Fprintln(w, strings.Repeat("-", 3)+"\t"+
-		strings.Repeat("-", 20)+"\t"+
-		strings.Repeat("-", 30)+"\t"+
-		strings.Repeat("-", 19)+"\t"+
-		strings.Repeat("-", 12)+"\t"+
-		strings.Repeat("-", 12))
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	separators := make([]string, len(columns))
+	for i, name := range columns {
+		headers[i] = tableColumns[name].header
+		// Separator line using dashes, adjusted to match column widths
+		separators[i] = strings.Repeat("-", tableColumns[name].width)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	fmt.Fprintln(w, strings.Join(separators, "\t"))
 
 	for _, task := range data {
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
-			task.ID,
-			task.Title,
-			task.Description,
-			task.Status,
-			task.CreatedAt,
-			task.UpdatedAt,
-		)
+		values := make([]string, len(columns))
+		for i, name := range columns {
+			values[i] = tableColumns[name].value(task)
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
 	}
 	w.Flush()
 }
@@ -166,38 +202,26 @@ func formatInJSON(data []DBTask) {
 }
 
 type DBTask struct {
+	Workspace   string `json:"workspace,omitempty"`
 	ID          int    `json:"id"`
+	UID         string `json:"uid"`
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Status      string `json:"status"`
+	Project     string `json:"project,omitempty"`
+	Tags        string `json:"tags,omitempty"`
+	Tracked     string `json:"tracked,omitempty"`
 	CreatedAt   string `json:"created_at"`
 	UpdatedAt   string `json:"updated_at"`
+	DeletedAt   string `json:"deleted_at,omitempty"`
 }
 
-func getRowData(rows *sql.Rows) []DBTask {
-	tasks := make([]DBTask, 0)
-	for rows.Next() {
-		var id int
-		var title, description, status, created_at, updated_at string
-
-		err := rows.Scan(&id, &title, &description, &status, &created_at, &updated_at)
-		if err != nil {
-			fmt.Printf("Error scanning row: %v", err)
-			os.Exit(1)
-		}
-
-		parsedCreatedDate, err := time.Parse("2006-01-02T15:04:05Z", created_at)
-		if err != nil {
-			fmt.Printf("Error parsing created_at: %v", err)
-			os.Exit(1)
-		}
-
-		parsedUpdatedDate, err := time.Parse("2006-01-02T15:04:05Z", updated_at)
-		if err != nil {
-			fmt.Printf("Error parsing updated_at: %v", err)
-			os.Exit(1)
-		}
-
+// getDisplayData shortens long fields and formats timestamps following the
+// date_format setting.
+func getDisplayData(data []models.Task) []DBTask {
+	tasks := make([]DBTask, 0, len(data))
+	for _, item := range data {
+		title, description := item.Title, item.Description
 		if len(title) > 20 {
 			title = title[:17] + "..."
 		}
@@ -205,56 +229,32 @@ func getRowData(rows *sql.Rows) []DBTask {
 			description = description[:27] + "..."
 		}
 
-		task := DBTask{
-			ID:          id,
-			Title:       title,
-			Description: description,
-			Status:      status,
-			CreatedAt:   timediff.TimeDiff(parsedCreatedDate),
-			UpdatedAt:   timediff.TimeDiff(parsedUpdatedDate),
+		var deletedAt string
+		if item.DeletedAt != nil {
+			deletedAt = formatDate(*item.DeletedAt)
 		}
 
-		tasks = append(tasks, task)
+		tasks = append(tasks, DBTask{
+			ID:          item.ID,
+			UID:         item.UID,
+			Title:       title,
+			Description: description,
+			Status:      item.Status,
+			Project:     item.Project,
+			Tags:        strings.Join(item.Tags, ","),
+			CreatedAt:   formatDate(item.CreatedAt),
+			UpdatedAt:   formatDate(item.UpdatedAt),
+			DeletedAt:   deletedAt,
+		})
 	}
 	return tasks
 }
 
-func getDataFromCSVFile(csvData [][]string) []DBTask {
-	tasks := make([]DBTask, 0)
-	// skip headers
-	restOfContent := csvData[1:]
-	for _, content := range restOfContent {
-		if len(content) >= 6 {
-			var id int
-
-			id, errInnerId := strconv.Atoi(content[0])
-			if errInnerId != nil {
-				fmt.Printf("Failed to parse the ID: %v", errInnerId)
-				break
-			}
-			parsedCreatedDate, err := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", content[4])
-			if err != nil {
-				fmt.Printf("Error parsing created_at: %v", err)
-				os.Exit(1)
-			}
-
-			parsedUpdatedDate, err := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", content[5])
-			if err != nil {
-				fmt.Printf("Error parsing updated_at: %v", err)
-				os.Exit(1)
-			}
-			task := DBTask{
-				ID:          id,
-				Title:       content[1],
-				Description: content[2],
-				Status:      content[3],
-				CreatedAt:   timediff.TimeDiff(parsedCreatedDate),
-				UpdatedAt:   timediff.TimeDiff(parsedUpdatedDate),
-			}
-
-			tasks = append(tasks, task)
-		}
+// formatDate formats a time as relative to now, or with the Go time layout
+// of the date_format setting.
+func formatDate(t time.Time) string {
+	if cfg.DateFormat == "" || cfg.DateFormat == "relative" {
+		return timediff.TimeDiff(t)
 	}
-
-	return tasks
-}
\ No newline at end of file
+	return t.Local().Format(cfg.DateFormat)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// remoteStore reads and writes the tasks of a tasks-cli server through its
+// JSON API. The server records the history and webhooks of the changes, so
+// they are not recorded locally.
+type remoteStore struct {
+	url    string
+	client *tasks.Client
+}
+
+func newRemoteStore(baseURL string, token string) *remoteStore {
+	return &remoteStore{url: baseURL, client: tasks.NewClient(baseURL, tasks.WithToken(token))}
+}
+
+// getRemoteStore returns the remote store when the config file selects the
+// remote backend, nil otherwise.
+func getRemoteStore() taskStore {
+	if cfg.Backend != "remote" {
+		return nil
+	}
+	return newRemoteStore(cfg.Remote.URL, cfg.Remote.Token)
+}
+
+func (s *remoteStore) Name() string {
+	if parsed, err := url.Parse(s.url); err == nil && parsed.Host != "" {
+		return fmt.Sprintf("Remote (%s)", parsed.Host)
+	}
+	return "Remote"
+}
+
+func (s *remoteStore) Key() string {
+	return "remote"
+}
+
+func (s *remoteStore) List() ([]models.Task, error) {
+	return s.list(tasks.ViewActive)
+}
+
+func (s *remoteStore) Trashed() ([]models.Task, error) {
+	return s.list(tasks.ViewTrashed)
+}
+
+func (s *remoteStore) Archived() ([]models.Task, error) {
+	return s.list(tasks.ViewArchived)
+}
+
+// list fetches every page of a view of the tasks.
+func (s *remoteStore) list(view tasks.View) ([]models.Task, error) {
+	var list []models.Task
+	for task, err := range s.client.All(context.Background(), tasks.ListOptions{View: view}) {
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, task)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func (s *remoteStore) Add(task models.Task) (models.Task, error) {
+	return s.client.Create(context.Background(), task)
+}
+
+func (s *remoteStore) Update(task models.Task) error {
+	_, err := s.client.Update(context.Background(), task)
+	return err
+}
+
+func (s *remoteStore) Delete(id int) error {
+	return s.client.Purge(context.Background(), strconv.Itoa(id))
+}
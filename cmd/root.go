@@ -4,18 +4,49 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/chzyer/readline"
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/config"
 	"github.com/unf6/testing/pkg/database"
 )
 
+var (
+	// cfgFile, cfgProfile, cfgWorkspace, cfgDB and cfgCSV hold the --config,
+	// --profile, --workspace, --db and --csv flags, see loadConfig.
+	cfgFile      string
+	cfgProfile   string
+	cfgWorkspace string
+	cfgDB        string
+	cfgCSV       string
+	// cfg holds the settings in effect.
+	cfg config.Settings
+	// workspace holds the paths of the workspace in use.
+	workspace config.Workspace
+)
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "tasks-cli",
 	Short: "A CLI tool for managing tasks in a Database (sqlite)/CSV file.",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
-		database.ConnectDB() // Initialize the database
+		loadConfig()
+		operationCommand = cmd.Name()
+		if cfg.Backend == "remote" {
+			// The server keeps the tasks, the local database is only
+			// opened by the commands using it.
+			database.UseDB(workspace.DB)
+			return
+		}
+		database.ConnectDB(workspace.DB) // Initialize the database
+		if cmd.Annotations["writes"] != "" {
+			autoArchive()
+		}
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		database.CloseDB() // Close the database connection
@@ -36,7 +67,11 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.tasks-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is config.yaml in the config directory, or $TASKS_CLI_CONFIG)")
+	rootCmd.PersistentFlags().StringVarP(&cfgWorkspace, "workspace", "w", "", "workspace to use (default is the workspace setting)")
+	rootCmd.PersistentFlags().StringVar(&cfgProfile, "profile", "", "profile of the config file to use (default is $TASKS_CLI_PROFILE or the profile setting)")
+	rootCmd.PersistentFlags().StringVar(&cfgDB, "db", "", "database to use instead of the one of the workspace (default is $TASKS_CLI_DB)")
+	rootCmd.PersistentFlags().StringVar(&cfgCSV, "csv", "", "CSV file to use instead of the one of the workspace (default is $TASKS_CLI_CSV)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -44,6 +79,115 @@ func init() {
 	rootCmd.AddCommand(completionCmd)
 }
 
+// loadConfig resolves the settings and workspace selected with --config,
+// --profile and --workspace, and exits when they are invalid. Without
+// --workspace, a .tasks store found from the current directory is used
+// instead, see config.Discover. --db and --csv, or TASKS_CLI_DB and
+// TASKS_CLI_CSV, replace the files of the workspace, a database given this
+// way has its own backup directory beside it.
+func loadConfig() {
+	if cfgFile == "" {
+		cfgFile = config.Path()
+	}
+
+	file, err := config.Load(cfgFile)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	settings, err := file.Resolve(cfgProfile)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+
+	if cfgWorkspace != "" {
+		settings.Workspace = cfgWorkspace
+	}
+	workspace, err = file.Workspace(settings.Workspace)
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	if local, ok := repositoryWorkspace(); ok {
+		workspace = local
+	}
+	db := workspace.DB
+	if err := overridePath(&workspace.DB, cfgDB, "TASKS_CLI_DB"); err != nil {
+		fmt.Printf("%s --db: %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+	if workspace.DB != db {
+		// Backups of another database must not be rotated with the ones
+		// of the workspace: other.db is backed up to other-backups.
+		name := strings.TrimSuffix(filepath.Base(workspace.DB), filepath.Ext(workspace.DB))
+		workspace.Backups = filepath.Join(filepath.Dir(workspace.DB), name+"-backups")
+	}
+	if err := overridePath(&workspace.CSV, cfgCSV, "TASKS_CLI_CSV"); err != nil {
+		fmt.Printf("%s --csv: %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+
+	cfg = settings
+	applyColors()
+	configureKeyring()
+}
+
+// repositoryWorkspace returns the store of the repository found from the
+// current directory, which replaces the workspace unless one is selected with
+// --workspace or TASKS_CLI_WORKSPACE.
+func repositoryWorkspace() (config.Workspace, bool) {
+	if cfgWorkspace != "" || os.Getenv("TASKS_CLI_WORKSPACE") != "" {
+		return config.Workspace{}, false
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return config.Workspace{}, false
+	}
+	return config.Discover(dir)
+}
+
+// overridePath replaces path with value, or the env variable when value is
+// empty, made absolute.
+func overridePath(path *string, value string, env string) error {
+	if value == "" {
+		value = os.Getenv(env)
+	}
+	if value == "" {
+		return nil
+	}
+
+	absolute, err := filepath.Abs(value)
+	if err != nil {
+		return err
+	}
+	*path = absolute
+	return nil
+}
+
+// applyColors removes the colors of the prompt icons unless colorsEnabled.
+func applyColors() {
+	if !colorsEnabled() {
+		promptui.IconInitial = "?"
+		promptui.IconGood = "✔"
+		promptui.IconWarn = "⚠"
+		promptui.IconBad = "✗"
+		promptui.IconSelect = "▸"
+	}
+}
+
+// colorsEnabled reports whether output is colored following the colors
+// setting: always, never, or auto for terminals when NO_COLOR is not set.
+func colorsEnabled() bool {
+	switch cfg.Colors {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && readline.IsTerminal(int(os.Stdout.Fd()))
+}
+
 var completionCmd = &cobra.Command{
 	Use:   "completion [bash|zsh|fish|powershell]",
 	Short: "Generate completion script",
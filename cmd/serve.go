@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/api"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the tasks of the database over a JSON HTTP API",
+	Long: `Serve the tasks of the Database (sqlite) over HTTP:
+
+  GET    /api/tasks       list tasks, filtered with ?status=, ?q= and ?view=
+                          (active, archived, trashed or all), paginated
+                          with ?limit= and ?offset=
+  POST   /api/tasks       create a task from its title, description, status,
+                          project and tags
+  GET    /api/tasks/{id}  get a task by ID or UID prefix
+  PATCH  /api/tasks/{id}  update the fields of a task
+  DELETE /api/tasks/{id}  move a task to the trash, ?purge=true deletes it for good
+  GET    /api/events      stream created, updated and deleted events of tasks as
+                          server-sent events, including changes made by the
+                          CLI, resumable with the Last-Event-ID header
+  GET    /                web UI with a task table, a kanban board and forms
+
+Requests must send an API token created with the token command as
+"Authorization: Bearer <token>", changes need a token with the write scope.
+Every request is logged with the name of its token, which is also recorded
+as the author of the changes in the task history.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		addr, _ := cmd.Flags().GetString("addr")
+		noAuth, _ := cmd.Flags().GetBool("no-auth")
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// The passphrase of an encrypted database is asked before serving.
+		if _, err := contentKey(); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		var mu sync.Mutex
+		server := &http.Server{
+			Addr:    addr,
+			Handler: newAPIServer(newSQLiteStore(), noAuth, &mu).Handler(),
+			// Cancels the event streams on shutdown.
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		go deliverWebhooksUntilDone(ctx, &mu)
+		go archiveUntilDone(ctx, &mu)
+
+		if noAuth {
+			fmt.Printf("%s Authentication is disabled, anyone reaching %s can change tasks\n", promptui.IconWarn, addr)
+		} else if tokens, err := queryTokens(`WHERE revoked_at IS NULL`); err == nil && len(tokens) == 0 {
+			fmt.Printf("%s No API tokens yet, create one with the token create command\n", promptui.IconWarn)
+		}
+		fmt.Printf("%s Serving tasks on http://%s\n", promptui.IconGood, addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Printf("%s Server failed: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().Bool("no-auth", false, "Accept requests without an API token")
+	rootCmd.AddCommand(serveCmd)
+}
+
+// newAPIServer returns the API of the serve command on store, mu serializes
+// its database access with the webhook delivery.
+func newAPIServer(store taskStore, noAuth bool, mu *sync.Mutex) *api.Server {
+	service := tasks.NewService(store)
+	server := &api.Server{
+		Service:   service,
+		Events:    &eventStream{store: store, service: service, mu: mu},
+		UI:        webUIHandler(),
+		Operation: beginOperation,
+		Mu:        mu,
+	}
+	if !noAuth {
+		server.Authenticate = func(secret string) (*api.Token, error) {
+			token, err := authenticateToken(secret)
+			if token == nil {
+				return nil, err
+			}
+			return &api.Token{Name: token.Name, Write: token.canWrite()}, nil
+		}
+	}
+	return server
+}
+
+// beginOperation records the token of a request as the author of its
+// changes. The function it returns makes the next request journal its
+// changes as a separate operation, so undo reverts one request at a time.
+func beginOperation(token *api.Token) func() {
+	if token != nil {
+		operationActor = "token:" + token.Name
+	}
+	return func() {
+		operationID = 0
+		operationActor = ""
+	}
+}
+
+// deliverWebhooksUntilDone delivers the webhook outbox until the server
+// shuts down.
+func deliverWebhooksUntilDone(ctx context.Context, mu *sync.Mutex) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// The lock is released during the requests, a slow receiver
+			// does not hold up the API.
+			if _, _, err := deliverWebhooks(ctx, 0, mu, keyring.Key); err != nil {
+				log.Printf("webhook delivery failed: %v", err)
+			}
+		}
+	}
+}
+
+// archiveUntilDone runs the automatic archiving now and then every
+// archiveInterval until the server shuts down, autoArchive itself only
+// archives once a day.
+func archiveUntilDone(ctx context.Context, mu *sync.Mutex) {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+	for {
+		mu.Lock()
+		autoArchive()
+		mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show the details of a task",
+	Long:  `Show every field of a task along with the time tracked on it.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		format := outputFormat(cmd)
+
+		store := promptStore("Where is the task stored?")
+		task, err := findTask(store, args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		entries, err := getTaskTimeEntries(store.Key(), task)
+		if err != nil {
+			fmt.Printf("%s Failed to fetch tracked time: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		switch format {
+		case "json":
+			showInJSON(task, entries)
+		default:
+			showInText(task, entries)
+		}
+	},
+}
+
+func init() {
+	showCmd.Flags().StringP("format", "f", "text", "Output format: text, json")
+	rootCmd.AddCommand(showCmd)
+}
+
+func showInText(task models.Task, entries []timeEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%d\n", task.ID)
+	fmt.Fprintf(w, "UID:\t%s\n", task.UID)
+	fmt.Fprintf(w, "Title:\t%s\n", task.Title)
+	fmt.Fprintf(w, "Description:\t%s\n", task.Description)
+	fmt.Fprintf(w, "Status:\t%s\n", task.Status)
+	if task.Project != "" {
+		fmt.Fprintf(w, "Project:\t%s\n", task.Project)
+	}
+	if len(task.Tags) > 0 {
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(task.Tags, ", "))
+	}
+	fmt.Fprintf(w, "Created at:\t%s\n", task.CreatedAt.Local().Format(time.RFC1123))
+	fmt.Fprintf(w, "Updated at:\t%s\n", task.UpdatedAt.Local().Format(time.RFC1123))
+	fmt.Fprintf(w, "Tracked:\t%s\n", formatTracked(totalTracked(entries)))
+	w.Flush()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tENDED\tDURATION")
+	for _, entry := range entries {
+		ended := "running"
+		if entry.EndedAt != nil {
+			ended = entry.EndedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", entry.StartedAt.Local().Format("2006-01-02 15:04"), ended, formatTracked(entry.Duration()))
+	}
+	w.Flush()
+}
+
+func showInJSON(task models.Task, entries []timeEntry) {
+	data := struct {
+		models.Task
+		Tracked     string      `json:"tracked"`
+		TimeEntries []timeEntry `json:"time_entries"`
+	}{task, formatTracked(totalTracked(entries)), entries}
+
+	jsonData, err := json.MarshalIndent(data, "", " ")
+	if err != nil {
+		fmt.Printf("Failed to change data to JSON: %v", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonData))
+}
+
+// totalTracked sums the durations of the entries.
+func totalTracked(entries []timeEntry) time.Duration {
+	var total time.Duration
+	for _, entry := range entries {
+		total += entry.Duration()
+	}
+	return total
+}
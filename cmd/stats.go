@@ -0,0 +1,302 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+)
+
+// taskFlow holds when a task reached the states flow metrics are based on.
+type taskFlow struct {
+	Task        models.Task
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+	// Statuses are the status changes of the history, oldest first.
+	Statuses []statusChange
+}
+
+// statusChange is a status change of a task recorded in its history.
+type statusChange struct {
+	At   time.Time
+	From string
+	To   string
+}
+
+type weekStats struct {
+	Week      string `json:"week"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+type agingTask struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Age    string `json:"age"`
+	Days   int    `json:"days"`
+}
+
+type statsReport struct {
+	Total            int            `json:"total"`
+	ByStatus         map[string]int `json:"by_status"`
+	Weeks            []weekStats    `json:"weeks"`
+	AvgLeadTimeDays  float64        `json:"avg_lead_time_days"`
+	AvgCycleTimeDays float64        `json:"avg_cycle_time_days"`
+	Aging            []agingTask    `json:"aging_wip"`
+}
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show task counts and flow metrics",
+	Long: `Show the number of tasks per status, tasks created and completed per week,
+the average lead time (created to completed) and cycle time (in-progress to
+completed), and how long the work in progress has been waiting.
+Status changes are read from the task history; archived tasks are included.`,
+	Args:   cobra.NoArgs,
+	PreRun: requireLocalBackend,
+	Run: func(cmd *cobra.Command, args []string) {
+		format := outputFormat(cmd)
+		weeks, _ := cmd.Flags().GetInt("weeks")
+
+		store := promptStore("Which database should we compute statistics for?")
+		flows, err := getTaskFlows(store)
+		if err != nil {
+			fmt.Printf("%s Failed to compute statistics: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		report := buildStats(flows, weeks, time.Now())
+		switch format {
+		case "json":
+			jsonData, err := json.MarshalIndent(report, "", " ")
+			if err != nil {
+				fmt.Printf("Failed to change data to JSON: %v", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(jsonData))
+		default:
+			statsInTable(report)
+		}
+	},
+}
+
+func init() {
+	statsCmd.Flags().StringP("format", "f", "table", "Output format: table, json")
+	statsCmd.Flags().Int("weeks", 8, "Number of weeks to show throughput for")
+	rootCmd.AddCommand(statsCmd)
+}
+
+// getTaskFlows returns the tasks of the store, archived ones included, with
+// their first start and last completion taken from the status history.
+func getTaskFlows(store taskStore) ([]taskFlow, error) {
+	tasks, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	archived, err := store.Archived()
+	if err != nil {
+		return nil, err
+	}
+	tasks = append(tasks, archived...)
+
+	// The history values are encrypted along the tasks.
+	key, err := contentKey()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.GetDB().Query(`SELECT COALESCE(task_uid, ''), COALESCE(old_value, ''), new_value, changed_at FROM task_history
+		WHERE backend = ? AND action = 'update' AND field = 'status' ORDER BY id`, store.Key())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	started := make(map[string]time.Time)
+	completed := make(map[string]time.Time)
+	changes := make(map[string][]statusChange)
+	for rows.Next() {
+		var uid, from, status string
+		var changedAt time.Time
+		if err := rows.Scan(&uid, &from, &status, &changedAt); err != nil {
+			return nil, err
+		}
+		if from, err = key.DecryptString(from, historyLocation("old_value", uid)); err != nil {
+			return nil, err
+		}
+		if status, err = key.DecryptString(status, historyLocation("new_value", uid)); err != nil {
+			return nil, err
+		}
+		changes[uid] = append(changes[uid], statusChange{At: changedAt, From: from, To: status})
+		switch status {
+		case "in-progress":
+			if _, ok := started[uid]; !ok {
+				started[uid] = changedAt
+			}
+		case "completed":
+			completed[uid] = changedAt
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	flows := make([]taskFlow, 0, len(tasks))
+	for _, task := range tasks {
+		flow := taskFlow{Task: task, Statuses: changes[task.UID]}
+		if at, ok := started[task.UID]; ok {
+			flow.StartedAt = &at
+		}
+		if task.Status == "completed" {
+			// Tasks completed before the history existed fall back to their last update.
+			at, ok := completed[task.UID]
+			if !ok {
+				at = task.UpdatedAt
+			}
+			flow.CompletedAt = &at
+		}
+		flows = append(flows, flow)
+	}
+	return flows, nil
+}
+
+// statusAt returns the status the task of the flow had at t. Before its
+// first recorded change, a task had the status that change left, and a task
+// completed without history was open until its completion.
+func (flow taskFlow) statusAt(t time.Time) string {
+	status := flow.Task.Status
+	switch {
+	case len(flow.Statuses) > 0:
+		status = flow.Statuses[0].From
+	case flow.CompletedAt != nil && t.Before(*flow.CompletedAt):
+		return "pending"
+	}
+	for _, change := range flow.Statuses {
+		if change.At.Before(t) {
+			status = change.To
+		}
+	}
+	return status
+}
+
+func buildStats(flows []taskFlow, weeks int, now time.Time) statsReport {
+	report := statsReport{
+		Total:    len(flows),
+		ByStatus: make(map[string]int),
+		Aging:    make([]agingTask, 0),
+	}
+
+	weekIndex := make(map[string]int)
+	for i := weeks - 1; i >= 0; i-- {
+		key := isoWeek(now.AddDate(0, 0, -7*i))
+		weekIndex[key] = len(report.Weeks)
+		report.Weeks = append(report.Weeks, weekStats{Week: key})
+	}
+
+	var leadTotal, cycleTotal time.Duration
+	var leadCount, cycleCount int
+	for _, flow := range flows {
+		report.ByStatus[flow.Task.Status]++
+
+		if i, ok := weekIndex[isoWeek(flow.Task.CreatedAt)]; ok {
+			report.Weeks[i].Created++
+		}
+
+		if flow.CompletedAt != nil {
+			if i, ok := weekIndex[isoWeek(*flow.CompletedAt)]; ok {
+				report.Weeks[i].Completed++
+			}
+			leadTotal += flow.CompletedAt.Sub(flow.Task.CreatedAt)
+			leadCount++
+			if flow.StartedAt != nil && flow.StartedAt.Before(*flow.CompletedAt) {
+				cycleTotal += flow.CompletedAt.Sub(*flow.StartedAt)
+				cycleCount++
+			}
+			continue
+		}
+
+		if flow.Task.Status == "in-progress" {
+			since := flow.Task.CreatedAt
+			if flow.StartedAt != nil {
+				since = *flow.StartedAt
+			}
+			age := now.Sub(since)
+			report.Aging = append(report.Aging, agingTask{
+				ID:     flow.Task.ID,
+				Title:  flow.Task.Title,
+				Status: flow.Task.Status,
+				Age:    formatDays(age),
+				Days:   int(age.Hours() / 24),
+			})
+		}
+	}
+
+	if leadCount > 0 {
+		report.AvgLeadTimeDays = (leadTotal / time.Duration(leadCount)).Hours() / 24
+	}
+	if cycleCount > 0 {
+		report.AvgCycleTimeDays = (cycleTotal / time.Duration(cycleCount)).Hours() / 24
+	}
+	sort.Slice(report.Aging, func(i, j int) bool { return report.Aging[i].Days > report.Aging[j].Days })
+	return report
+}
+
+func statsInTable(report statsReport) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tTASKS")
+	statuses := make([]string, 0, len(report.ByStatus))
+	for status := range report.ByStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	for _, status := range statuses {
+		fmt.Fprintf(w, "%s\t%d\n", status, report.ByStatus[status])
+	}
+	fmt.Fprintf(w, "total\t%d\n", report.Total)
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WEEK\tCREATED\tCOMPLETED")
+	for _, week := range report.Weeks {
+		fmt.Fprintf(w, "%s\t%d\t%d\n", week.Week, week.Created, week.Completed)
+	}
+	w.Flush()
+
+	fmt.Println()
+	fmt.Printf("Average lead time:  %.1f days\n", report.AvgLeadTimeDays)
+	fmt.Printf("Average cycle time: %.1f days\n", report.AvgCycleTimeDays)
+
+	if len(report.Aging) == 0 {
+		return
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWORK IN PROGRESS\tAGE")
+	for _, task := range report.Aging {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", task.ID, task.Title, task.Age)
+	}
+	w.Flush()
+}
+
+// isoWeek formats the ISO week of t, e.g. 2024-W07.
+func isoWeek(t time.Time) string {
+	year, week := t.Local().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// formatDays formats a duration in whole days, or hours below a day.
+func formatDays(d time.Duration) string {
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/unf6/testing/models"
+)
+
+func TestBuildStats(t *testing.T) {
+	// A Wednesday noon, far from the week boundaries in any time zone.
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+	days := func(n int) *time.Time {
+		at := now.AddDate(0, 0, -n)
+		return &at
+	}
+	task := func(id int, status string, createdDaysAgo int) models.Task {
+		return models.Task{ID: id, Title: "Task", Status: status, CreatedAt: *days(createdDaysAgo)}
+	}
+
+	tests := []struct {
+		name  string
+		flows []taskFlow
+		lead  float64
+		cycle float64
+		// completed is the number of completions per week, oldest first.
+		completed []int
+		aging     []int
+	}{
+		{
+			name:      "lead and cycle time",
+			flows:     []taskFlow{{Task: task(1, "completed", 10), StartedAt: days(6), CompletedAt: days(2)}},
+			lead:      8,
+			cycle:     4,
+			completed: []int{0, 1},
+		},
+		{
+			name:      "completed without a start",
+			flows:     []taskFlow{{Task: task(1, "completed", 10), CompletedAt: days(7)}},
+			lead:      3,
+			completed: []int{1, 0},
+		},
+		{
+			name: "start after the completion",
+			flows: []taskFlow{
+				{Task: task(1, "completed", 10), StartedAt: days(1), CompletedAt: days(2)},
+				{Task: task(2, "completed", 6), StartedAt: days(4), CompletedAt: days(2)},
+			},
+			lead:      6,
+			cycle:     2,
+			completed: []int{0, 2},
+		},
+		{
+			name: "work in progress",
+			flows: []taskFlow{
+				{Task: task(1, "in-progress", 10), StartedAt: days(1)},
+				{Task: task(2, "in-progress", 5)},
+				{Task: task(3, "pending", 20)},
+			},
+			completed: []int{0, 0},
+			aging:     []int{2, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := buildStats(tt.flows, 2, now)
+			if report.Total != len(tt.flows) {
+				t.Errorf("Total = %d, want %d", report.Total, len(tt.flows))
+			}
+			if report.AvgLeadTimeDays != tt.lead {
+				t.Errorf("AvgLeadTimeDays = %v, want %v", report.AvgLeadTimeDays, tt.lead)
+			}
+			if report.AvgCycleTimeDays != tt.cycle {
+				t.Errorf("AvgCycleTimeDays = %v, want %v", report.AvgCycleTimeDays, tt.cycle)
+			}
+			var completed []int
+			for _, week := range report.Weeks {
+				completed = append(completed, week.Completed)
+			}
+			if !reflect.DeepEqual(completed, tt.completed) {
+				t.Errorf("completed per week = %v, want %v", completed, tt.completed)
+			}
+			var aging []int
+			for _, task := range report.Aging {
+				aging = append(aging, task.ID)
+			}
+			if !reflect.DeepEqual(aging, tt.aging) {
+				t.Errorf("aging = %v, want %v", aging, tt.aging)
+			}
+		})
+	}
+}
+
+func TestTaskFlowsUpdatedAtFallback(t *testing.T) {
+	useTestWorkspace(t)
+	store, _ := getStore("csv")
+
+	updatedAt := time.Now().UTC().AddDate(0, 0, -3).Truncate(time.Second)
+	// Tasks imported as completed have no status change in their history.
+	imported, err := store.Add(models.Task{Title: "Imported", Status: "completed", CreatedAt: updatedAt.AddDate(0, 0, -2), UpdatedAt: updatedAt})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	completed, err := store.Add(models.Task{Title: "Completed", Status: "pending", CreatedAt: updatedAt, UpdatedAt: updatedAt})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	completed.Status = "completed"
+	if err := store.Update(completed); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	flows, err := getTaskFlows(store)
+	if err != nil {
+		t.Fatalf("getTaskFlows: %v", err)
+	}
+	for _, flow := range flows {
+		if flow.CompletedAt == nil {
+			t.Fatalf("task %q has no completion", flow.Task.Title)
+		}
+		switch flow.Task.ID {
+		case imported.ID:
+			if !flow.CompletedAt.Equal(updatedAt) {
+				t.Errorf("imported task completed at %v, want its update %v", flow.CompletedAt, updatedAt)
+			}
+			// The lead time of the imported task runs up to its update.
+			if report := buildStats([]taskFlow{flow}, 1, time.Now()); report.AvgLeadTimeDays != 2 {
+				t.Errorf("AvgLeadTimeDays = %v, want 2", report.AvgLeadTimeDays)
+			}
+		case completed.ID:
+			if time.Since(*flow.CompletedAt) > time.Minute {
+				t.Errorf("completed task completed at %v, want the status change", flow.CompletedAt)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// taskStatuses are the statuses offered when creating and editing tasks.
+var taskStatuses = tasks.Statuses
+
+// taskStore is a backend tasks can be read from and written to.
+type taskStore = tasks.Store
+
+// taskNotFoundError is returned when no task matches a reference.
+type taskNotFoundError = tasks.NotFoundError
+
+// getStore returns the store for a backend name as used by the --from/--to flags.
+func getStore(name string) (taskStore, error) {
+	switch name {
+	case "sqlite", "db":
+		return newSQLiteStore(), nil
+	case "csv":
+		return newCSVStore(), nil
+	case "remote":
+		if cfg.Remote.URL == "" {
+			return nil, fmt.Errorf("no remote url in the config file")
+		}
+		return newRemoteStore(cfg.Remote.URL, cfg.Remote.Token), nil
+	}
+	return nil, fmt.Errorf("unknown backend %q, valid options are 'sqlite', 'csv' or 'remote'", name)
+}
+
+// configuredStore returns the store of the backend setting, or nil when
+// commands should ask which one to use.
+func configuredStore() taskStore {
+	switch cfg.Backend {
+	case "sqlite":
+		return newSQLiteStore()
+	case "csv":
+		return newCSVStore()
+	case "remote":
+		return getRemoteStore()
+	}
+	return nil
+}
+
+// localBackendError returns an error when the backend setting is remote:
+// the server records the history and the journal of its changes, the
+// commands reading them locally would find none.
+func localBackendError(cmd *cobra.Command) error {
+	if cfg.Backend != "remote" {
+		return nil
+	}
+	return fmt.Errorf("%s reads the changes recorded locally and is not available with the remote backend, run it on the server", cmd.Name())
+}
+
+// requireLocalBackend is the PreRun of the commands reading the history or
+// the journal, it exits under the remote backend.
+func requireLocalBackend(cmd *cobra.Command, args []string) {
+	if err := localBackendError(cmd); err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+}
+
+// configuredChoice returns the answer of the store prompts matching the
+// backend setting, or an empty string when commands should ask.
+func configuredChoice() string {
+	switch cfg.Backend {
+	case "sqlite":
+		return "Database (sqlite)"
+	case "csv":
+		return "CSV File"
+	case "remote":
+		return "Remote"
+	}
+	return ""
+}
+
+// getCSVFilePath returns the path of the CSV store of the workspace, creating
+// its directory if needed.
+func getCSVFilePath() string {
+	dir := filepath.Dir(workspace.CSV)
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err = os.MkdirAll(dir, 0755)
+		if err != nil {
+			fmt.Printf("Failed to create config directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	return workspace.CSV
+}
+
+// newSQLiteStore returns the database store, recording its changes.
+func newSQLiteStore() taskStore {
+	return &tasks.SQLiteStore{DB: database.GetDB(), OnChange: recordChange, Keys: keyring.Key}
+}
+
+// newCSVStore returns the CSV file store, recording its changes.
+func newCSVStore() taskStore {
+	return &tasks.CSVStore{Path: getCSVFilePath(), OnChange: recordChange, Keys: keyring.Key}
+}
+
+// recordChange journals a change of the backend for undo, queues its webhooks
+// and adds it to the task history. before is nil for created tasks, after for
+// deleted ones.
+func recordChange(backend string, before *models.Task, after *models.Task) error {
+	if err := journal(backend, before, after); err != nil {
+		return err
+	}
+	if err := enqueueWebhooks(backend, before, after); err != nil {
+		return err
+	}
+
+	switch {
+	case before == nil:
+		return recordHistory(createdHistory(backend, *after))
+	case after == nil:
+		return recordHistory(deletedHistory(backend, *before))
+	}
+	return recordHistory(updatedHistory(backend, *before, *after)...)
+}
+
+// findTask looks a task that is not in the trash up by its integer ID or by
+// a unique prefix of its UID.
+func findTask(store taskStore, ref string) (models.Task, error) {
+	return tasks.Find(store, ref)
+}
+
+// matchTask picks the task with the integer ID or unique UID prefix ref.
+func matchTask(list []models.Task, ref string) (models.Task, error) {
+	return tasks.Match(list, ref)
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/api"
+	"github.com/unf6/testing/pkg/config"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+func TestLocalBackendCommands(t *testing.T) {
+	cfg = config.Defaults()
+	t.Cleanup(func() { cfg = config.Settings{} })
+
+	// These commands read the history and journal recorded locally.
+	for _, cmd := range []*cobra.Command{historyCmd, logCmd, statsCmd, chartCmd, undoCmd, redoCmd} {
+		if cmd.PreRun == nil {
+			t.Errorf("%s does not check the backend", cmd.Name())
+		}
+		cfg.Backend = "sqlite"
+		if err := localBackendError(cmd); err != nil {
+			t.Errorf("%s with the sqlite backend: %v", cmd.Name(), err)
+		}
+		cfg.Backend = "remote"
+		if err := localBackendError(cmd); err == nil || !strings.Contains(err.Error(), cmd.Name()) {
+			t.Errorf("%s with the remote backend: %v, want an error naming it", cmd.Name(), err)
+		}
+	}
+}
+
+func TestRemoteBackendLeavesNoDatabase(t *testing.T) {
+	dir := t.TempDir()
+	service := tasks.NewService(&tasks.CSVStore{Path: filepath.Join(dir, "server.csv")})
+	if _, err := service.Create(context.Background(), models.Task{Title: "Call Acme"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	server := httptest.NewServer((&api.Server{Service: service}).Handler())
+	defer server.Close()
+
+	db := filepath.Join(dir, "tasks.db")
+	t.Setenv("TASKS_CLI_CONFIG", filepath.Join(dir, "config.yaml"))
+	t.Setenv("TASKS_CLI_WORKSPACE", config.DefaultWorkspace)
+	t.Setenv("TASKS_CLI_BACKEND", "remote")
+	t.Setenv("TASKS_CLI_REMOTE_URL", server.URL)
+	t.Setenv("TASKS_CLI_DB", db)
+	t.Setenv("TASKS_CLI_CSV", filepath.Join(dir, "tasks.csv"))
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Cleanup(func() {
+		cfgFile = ""
+		cfg = config.Settings{}
+		database.UseDB("")
+	})
+
+	stdout := os.Stdout
+	os.Stdout, _ = os.Open(os.DevNull)
+	rootCmd.PersistentPreRun(listCmd, nil)
+	listCmd.Run(listCmd, nil)
+	rootCmd.PersistentPostRun(listCmd, nil)
+	os.Stdout.Close()
+	os.Stdout = stdout
+
+	if _, err := os.Stat(db); !os.IsNotExist(err) {
+		t.Errorf("list with the remote backend created the local database: %v", err)
+	}
+}
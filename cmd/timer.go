@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// timeEntry is a period of time spent on a task. EndedAt is nil while the
+// timer is running.
+type timeEntry struct {
+	ID        int        `json:"id"`
+	Backend   string     `json:"backend"`
+	TaskID    int        `json:"task_id"`
+	TaskUID   string     `json:"task_uid"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// Duration returns the length of the entry, up to now for a running timer.
+func (e timeEntry) Duration() time.Duration {
+	if e.EndedAt == nil {
+		return time.Since(e.StartedAt)
+	}
+	return e.EndedAt.Sub(e.StartedAt)
+}
+
+// trackedTaskKey identifies the task of time entries, whose UID is only
+// unique within its backend.
+func trackedTaskKey(backend string, uid string) string {
+	return backend + "/" + uid
+}
+
+// startCmd represents the start command
+var startCmd = &cobra.Command{
+	Use:   "start <id>",
+	Short: "Start tracking time on a task",
+	Long:  `Start a timer on a task. Only one timer can run at a time, stop it with the stop command.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if running, err := getRunningTimer(); err != nil {
+			fmt.Printf("%s Failed to check the running timer: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		} else if running != nil {
+			fmt.Printf("%s A timer is already running on task %d since %s, stop it first\n",
+				promptui.IconBad, running.TaskID, running.StartedAt.Local().Format("15:04"))
+			os.Exit(1)
+		}
+
+		store := promptStore("Where is the task stored?")
+		task, err := findTask(store, args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		_, err = database.GetDB().Exec(`INSERT INTO time_entries (backend, task_id, task_uid, started_at) VALUES (?, ?, ?, ?)`,
+			store.Key(), task.ID, task.UID, time.Now().UTC())
+		if err != nil {
+			fmt.Printf("%s Failed to start the timer: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Timer started on task %d: %s\n", promptui.IconGood, task.ID, task.Title)
+	},
+}
+
+// stopCmd represents the stop command
+var stopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running timer",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		running, err := getRunningTimer()
+		if err != nil {
+			fmt.Printf("%s Failed to check the running timer: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if running == nil {
+			fmt.Println("No timer is running.")
+			return
+		}
+
+		now := time.Now().UTC()
+		if _, err := database.GetDB().Exec(`UPDATE time_entries SET ended_at = ? WHERE id = ?`, now, running.ID); err != nil {
+			fmt.Printf("%s Failed to stop the timer: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		running.EndedAt = &now
+		fmt.Printf("%s Timer stopped on task %d after %s\n", promptui.IconGood, running.TaskID, formatTracked(running.Duration()))
+	},
+}
+
+// logTimeCmd represents the log-time command
+var logTimeCmd = &cobra.Command{
+	Use:   "log-time <id> <duration>",
+	Short: "Record time spent on a task",
+	Long:  `Record time spent on a task without running a timer, e.g. log-time 5 1h30m. The entry ends now.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		duration, err := utils.ParseDuration(args[1])
+		if err != nil || duration <= 0 {
+			fmt.Printf("%s Invalid duration %q, use a value like 45m or 1h30m\n", promptui.IconBad, args[1])
+			os.Exit(1)
+		}
+
+		store := promptStore("Where is the task stored?")
+		task, err := findTask(store, args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		endedAt := time.Now().UTC()
+		_, err = database.GetDB().Exec(`INSERT INTO time_entries (backend, task_id, task_uid, started_at, ended_at) VALUES (?, ?, ?, ?, ?)`,
+			store.Key(), task.ID, task.UID, endedAt.Add(-duration), endedAt)
+		if err != nil {
+			fmt.Printf("%s Failed to log time: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Logged %s on task %d\n", promptui.IconGood, formatTracked(duration), task.ID)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(startCmd)
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(logTimeCmd)
+}
+
+// getRunningTimer returns the running time entry, or nil when none is running.
+func getRunningTimer() (*timeEntry, error) {
+	entries, err := queryTimeEntries(database.GetDB(), `WHERE ended_at IS NULL`)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// getTaskTimeEntries returns the time entries of a task of the backend,
+// oldest first. Like getTrackedTotals, it leaves out the entries of the
+// tasks of other backends sharing its UID.
+func getTaskTimeEntries(backend string, task models.Task) ([]timeEntry, error) {
+	return queryTimeEntries(trackingDB(), `WHERE backend = ? AND task_uid = ? ORDER BY started_at`, backend, task.UID)
+}
+
+// trackingDB returns the database time is tracked in, or nil under the remote
+// backend while no time was tracked and it does not exist.
+func trackingDB() *sql.DB {
+	if cfg.Backend == "remote" {
+		if _, err := os.Stat(workspace.DB); err != nil {
+			return nil
+		}
+	}
+	return database.GetDB()
+}
+
+// queryTimeEntries returns the time entries of db matching where, none when
+// db is nil.
+func queryTimeEntries(db *sql.DB, where string, args ...interface{}) ([]timeEntry, error) {
+	if db == nil {
+		return nil, nil
+	}
+	rows, err := db.Query(`SELECT id, backend, task_id, task_uid, started_at, ended_at FROM time_entries `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []timeEntry
+	for rows.Next() {
+		var entry timeEntry
+		var endedAt sql.NullTime
+		if err := rows.Scan(&entry.ID, &entry.Backend, &entry.TaskID, &entry.TaskUID, &entry.StartedAt, &endedAt); err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			entry.EndedAt = &endedAt.Time
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// getTrackedTotals returns the time tracked on each task of the backend by UID.
+func getTrackedTotals(db *sql.DB, backend string) (map[string]time.Duration, error) {
+	entries, err := queryTimeEntries(db, `WHERE backend = ?`, backend)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]time.Duration)
+	for _, entry := range entries {
+		totals[entry.TaskUID] += entry.Duration()
+	}
+	return totals, nil
+}
+
+// formatTracked formats a tracked duration to the minute, e.g. 1h30m.
+func formatTracked(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d == 0 {
+		return "0m"
+	}
+	hours := int(d / time.Hour)
+	minutes := int((d % time.Hour) / time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	}
+	return fmt.Sprintf("%dh%02dm", hours, minutes)
+}
@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// timesheetRow is the time tracked for one group of the timesheet. The
+// fields of the groups the timesheet is not grouped by are empty.
+type timesheetRow struct {
+	Date     string        `json:"date,omitempty"`
+	Backend  string        `json:"backend,omitempty"`
+	TaskID   int           `json:"task_id,omitempty"`
+	TaskUID  string        `json:"task_uid,omitempty"`
+	Title    string        `json:"title,omitempty"`
+	Project  string        `json:"project,omitempty"`
+	Tag      string        `json:"tag,omitempty"`
+	Duration time.Duration `json:"-"`
+	Minutes  int           `json:"minutes"`
+	Hours    float64       `json:"hours"`
+}
+
+// timesheetGroups are the groups of the --group-by flag.
+type timesheetGroups struct {
+	day, task, project, tag bool
+}
+
+// timesheetCmd represents the timesheet command
+var timesheetCmd = &cobra.Command{
+	Use:   "timesheet",
+	Short: "Summarize tracked time over a date range",
+	Long: `Summarize the time tracked with start/stop and log-time between two dates,
+grouped by day, task, project and tag. Each time entry is rounded with --round
+before being summed, entries belong to the day they started on.
+
+Grouped by tag, the time of a task with several tags counts in each of them,
+the total counts it once.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fromFlag, _ := cmd.Flags().GetString("from")
+		toFlag, _ := cmd.Flags().GetString("to")
+		groupBy, _ := cmd.Flags().GetString("group-by")
+		roundFlag, _ := cmd.Flags().GetString("round")
+		roundMode, _ := cmd.Flags().GetString("round-mode")
+		format := outputFormat(cmd)
+
+		from, to, err := parseTimesheetRange(fromFlag, toFlag)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		var groups timesheetGroups
+		for _, group := range strings.Split(groupBy, ",") {
+			switch strings.TrimSpace(group) {
+			case "day":
+				groups.day = true
+			case "task":
+				groups.task = true
+			case "project":
+				groups.project = true
+			case "tag":
+				groups.tag = true
+			default:
+				fmt.Printf("%s Invalid group %q, valid options are 'day', 'task', 'project' and 'tag'\n", promptui.IconBad, group)
+				os.Exit(1)
+			}
+		}
+
+		var round time.Duration
+		if roundFlag != "" {
+			if round, err = utils.ParseDuration(roundFlag); err != nil {
+				fmt.Printf("%s %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+		}
+		if roundMode != "up" && roundMode != "down" && roundMode != "nearest" {
+			fmt.Printf("%s Invalid rounding mode %q, valid options are 'up', 'down' and 'nearest'\n", promptui.IconBad, roundMode)
+			os.Exit(1)
+		}
+
+		entries, err := queryTimeEntries(trackingDB(), `WHERE started_at >= ? AND started_at < ? ORDER BY started_at`, from.UTC(), to.UTC())
+		if err != nil {
+			fmt.Printf("%s Failed to fetch tracked time: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		titles, err := getTaskTitles()
+		if err != nil {
+			fmt.Printf("%s Failed to fetch tasks: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		rows, total := buildTimesheet(entries, titles, groups, func(d time.Duration) time.Duration {
+			return roundDuration(d, round, roundMode)
+		})
+
+		switch format {
+		case "json":
+			timesheetInJSON(rows)
+		case "csv":
+			timesheetInCSV(rows, groups)
+		default:
+			timesheetInTable(rows, total, groups)
+		}
+	},
+}
+
+func init() {
+	timesheetCmd.Flags().String("from", "", "First day of the timesheet, YYYY-MM-DD (default: monday of this week)")
+	timesheetCmd.Flags().String("to", "", "Last day of the timesheet, YYYY-MM-DD (default: today)")
+	timesheetCmd.Flags().String("group-by", "day,task", "Comma separated groups: day, task, project, tag")
+	timesheetCmd.Flags().String("round", "", "Round every time entry to a multiple of this duration, e.g. 15m")
+	timesheetCmd.Flags().String("round-mode", "up", "Rounding mode: up, down, nearest")
+	timesheetCmd.Flags().StringP("format", "f", "table", "Output format: table, csv, json")
+	rootCmd.AddCommand(timesheetCmd)
+}
+
+// parseTimesheetRange returns the local start of the first day and of the
+// day after the last one. Both default to the current week so far.
+func parseTimesheetRange(fromFlag string, toFlag string) (time.Time, time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+
+	from := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	if fromFlag != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", fromFlag, time.Local)
+		if err != nil {
+			return from, today, fmt.Errorf("invalid --from date %q, use YYYY-MM-DD", fromFlag)
+		}
+		from = parsed
+	}
+
+	to := today
+	if toFlag != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", toFlag, time.Local)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid --to date %q, use YYYY-MM-DD", toFlag)
+		}
+		to = parsed
+	}
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("--to is before --from")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// roundDuration rounds d to a multiple of unit following mode.
+func roundDuration(d time.Duration, unit time.Duration, mode string) time.Duration {
+	if unit <= 0 {
+		return d
+	}
+	switch mode {
+	case "down":
+		return d.Truncate(unit)
+	case "nearest":
+		return d.Round(unit)
+	}
+	if truncated := d.Truncate(unit); truncated != d {
+		return truncated + unit
+	}
+	return d
+}
+
+// getTaskTitles returns every task of every backend by trackedTaskKey,
+// trashed and archived tasks included.
+func getTaskTitles() (map[string]models.Task, error) {
+	tasks := make(map[string]models.Task)
+	for _, backend := range []string{"sqlite", "csv"} {
+		store, _ := getStore(backend)
+		for _, list := range []func() ([]models.Task, error){store.List, store.Archived, store.Trashed} {
+			found, err := list()
+			if err != nil {
+				return nil, err
+			}
+			for _, task := range found {
+				tasks[trackedTaskKey(store.Key(), task.UID)] = task
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// buildTimesheet sums the rounded entries by group, and returns the rows
+// with the total of the entries.
+func buildTimesheet(entries []timeEntry, tasks map[string]models.Task, groups timesheetGroups, round func(time.Duration) time.Duration) ([]timesheetRow, time.Duration) {
+	rows := make(map[string]*timesheetRow)
+	var keys []string
+	var total time.Duration
+
+	for _, entry := range entries {
+		duration := round(entry.Duration())
+		total += duration
+
+		task := tasks[trackedTaskKey(entry.Backend, entry.TaskUID)]
+		var row timesheetRow
+		if groups.day {
+			row.Date = entry.StartedAt.Local().Format("2006-01-02")
+		}
+		if groups.task {
+			row.Backend = entry.Backend
+			row.TaskID = entry.TaskID
+			row.TaskUID = entry.TaskUID
+			row.Title = task.Title
+		}
+		if groups.project {
+			row.Project = task.Project
+		}
+
+		tags := []string{""}
+		if groups.tag && len(task.Tags) > 0 {
+			tags = task.Tags
+		}
+		for _, tag := range tags {
+			row.Tag = tag
+			key := strings.Join([]string{row.Date, row.Project, row.Tag, row.Backend, row.TaskUID}, "|")
+			if _, ok := rows[key]; !ok {
+				added := row
+				rows[key] = &added
+				keys = append(keys, key)
+			}
+			rows[key].Duration += duration
+		}
+	}
+
+	sort.Strings(keys)
+	result := make([]timesheetRow, 0, len(keys))
+	for _, key := range keys {
+		row := *rows[key]
+		row.Minutes = int(row.Duration.Round(time.Minute) / time.Minute)
+		row.Hours = float64(row.Minutes) / 60
+		result = append(result, row)
+	}
+	return result, total
+}
+
+// timesheetLabel shows the empty project or tag of a group.
+func timesheetLabel(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func timesheetInTable(rows []timesheetRow, total time.Duration, groups timesheetGroups) {
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+
+	var headers []string
+	if groups.day {
+		headers = append(headers, "DATE")
+	}
+	if groups.project {
+		headers = append(headers, "PROJECT")
+	}
+	if groups.tag {
+		headers = append(headers, "TAG")
+	}
+	if groups.task {
+		headers = append(headers, "ID", "TITLE")
+	}
+	fmt.Fprintln(w, strings.Join(append(headers, "DURATION", "HOURS"), "\t"))
+
+	for _, row := range rows {
+		var columns []string
+		if groups.day {
+			columns = append(columns, row.Date)
+		}
+		if groups.project {
+			columns = append(columns, timesheetLabel(row.Project))
+		}
+		if groups.tag {
+			columns = append(columns, timesheetLabel(row.Tag))
+		}
+		if groups.task {
+			columns = append(columns, strconv.Itoa(row.TaskID), row.Title)
+		}
+		columns = append(columns, formatTracked(row.Duration), strconv.FormatFloat(row.Hours, 'f', 2, 64))
+		fmt.Fprintln(w, strings.Join(columns, "\t"))
+	}
+
+	footer := make([]string, len(headers))
+	footer[0] = "TOTAL"
+	minutes := int(total.Round(time.Minute) / time.Minute)
+	fmt.Fprintln(w, strings.Join(append(footer, formatTracked(total), strconv.FormatFloat(float64(minutes)/60, 'f', 2, 64)), "\t"))
+	w.Flush()
+}
+
+func timesheetInCSV(rows []timesheetRow, groups timesheetGroups) {
+	writer := csv.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	var headers []string
+	if groups.day {
+		headers = append(headers, "date")
+	}
+	if groups.project {
+		headers = append(headers, "project")
+	}
+	if groups.tag {
+		headers = append(headers, "tag")
+	}
+	if groups.task {
+		headers = append(headers, "backend", "task_id", "task_uid", "title")
+	}
+	writer.Write(append(headers, "minutes", "hours"))
+
+	for _, row := range rows {
+		var record []string
+		if groups.day {
+			record = append(record, row.Date)
+		}
+		if groups.project {
+			record = append(record, row.Project)
+		}
+		if groups.tag {
+			record = append(record, row.Tag)
+		}
+		if groups.task {
+			record = append(record, row.Backend, strconv.Itoa(row.TaskID), row.TaskUID, row.Title)
+		}
+		writer.Write(append(record, strconv.Itoa(row.Minutes), strconv.FormatFloat(row.Hours, 'f', 2, 64)))
+	}
+}
+
+func timesheetInJSON(rows []timesheetRow) {
+	jsonData, err := json.MarshalIndent(rows, "", " ")
+	if err != nil {
+		fmt.Printf("Failed to change data to JSON: %v", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(jsonData))
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+)
+
+func TestBuildTimesheet(t *testing.T) {
+	previous := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = previous })
+
+	// The UID of a task of the CSV file may be taken in the database too.
+	tasks := map[string]models.Task{
+		"sqlite/A": {ID: 1, UID: "A", Title: "Call Acme", Project: "acme", Tags: []string{"sales", "phone"}},
+		"sqlite/B": {ID: 2, UID: "B", Title: "Invoice Acme", Project: "acme", Tags: []string{"sales"}},
+		"sqlite/C": {ID: 3, UID: "C", Title: "Inbox"},
+		"csv/A":    {ID: 1, UID: "A", Title: "Call Globex", Project: "globex"},
+	}
+	entry := func(backend string, uid string, day int, minutes int) timeEntry {
+		started := time.Date(2024, 3, day, 9, 0, 0, 0, time.UTC)
+		ended := started.Add(time.Duration(minutes) * time.Minute)
+		return timeEntry{Backend: backend, TaskID: tasks[trackedTaskKey(backend, uid)].ID, TaskUID: uid, StartedAt: started, EndedAt: &ended}
+	}
+	entries := []timeEntry{
+		entry("sqlite", "A", 4, 20), entry("sqlite", "B", 4, 40), entry("sqlite", "C", 4, 5),
+		entry("sqlite", "A", 5, 60), entry("csv", "A", 5, 10),
+	}
+
+	tests := []struct {
+		groups timesheetGroups
+		want   []string
+	}{
+		{timesheetGroups{day: true}, []string{"2024-03-04 65", "2024-03-05 70"}},
+		{timesheetGroups{task: true}, []string{"csv/A 10", "sqlite/A 80", "sqlite/B 40", "sqlite/C 5"}},
+		{timesheetGroups{project: true}, []string{"acme 120", "globex 10", " 5"}},
+		// The time of a task counts in each of its tags, the tasks without
+		// project or tag come last.
+		{timesheetGroups{tag: true}, []string{"phone 80", "sales 120", " 15"}},
+		{timesheetGroups{day: true, project: true}, []string{"2024-03-04 acme 60", "2024-03-04  5", "2024-03-05 acme 60", "2024-03-05 globex 10"}},
+		{timesheetGroups{project: true, tag: true, task: true}, []string{"acme phone sqlite/A 80", "acme sales sqlite/A 80", "acme sales sqlite/B 40", "globex  csv/A 10", "  sqlite/C 5"}},
+	}
+	for _, test := range tests {
+		rows, total := buildTimesheet(entries, tasks, test.groups, func(d time.Duration) time.Duration { return d })
+		var got []string
+		for _, row := range rows {
+			var fields []interface{}
+			if test.groups.day {
+				fields = append(fields, row.Date)
+			}
+			if test.groups.project {
+				fields = append(fields, row.Project)
+			}
+			if test.groups.tag {
+				fields = append(fields, row.Tag)
+			}
+			if test.groups.task {
+				fields = append(fields, trackedTaskKey(row.Backend, row.TaskUID))
+			}
+			got = append(got, fmt.Sprintln(append(fields, row.Minutes)...))
+		}
+		want := make([]string, len(test.want))
+		for i, line := range test.want {
+			want[i] = line + "\n"
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("buildTimesheet(%+v) = %q, want %q", test.groups, got, want)
+		}
+		if total != 135*time.Minute {
+			t.Errorf("buildTimesheet(%+v) total = %v, want 2h15m", test.groups, total)
+		}
+	}
+}
+
+func TestRoundDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		mode string
+		want time.Duration
+	}{
+		{7 * time.Minute, "up", 15 * time.Minute},
+		{15 * time.Minute, "up", 15 * time.Minute},
+		{22 * time.Minute, "down", 15 * time.Minute},
+		{22 * time.Minute, "nearest", 15 * time.Minute},
+		{23 * time.Minute, "nearest", 30 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := roundDuration(test.d, 15*time.Minute, test.mode); got != test.want {
+			t.Errorf("roundDuration(%v, 15m, %s) = %v, want %v", test.d, test.mode, got, test.want)
+		}
+	}
+}
+
+func TestGetTaskTitles(t *testing.T) {
+	useTestWorkspace(t)
+	sqlite, _ := getStore("sqlite")
+	csvStore, _ := getStore("csv")
+
+	task, err := sqlite.Add(models.Task{Title: "Call Acme", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	// The tasks of both backends may share a UID, e.g. imported from the
+	// same export.
+	if _, err := csvStore.Add(models.Task{UID: task.UID, Title: "Call Globex", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	titles, err := getTaskTitles()
+	if err != nil {
+		t.Fatalf("getTaskTitles: %v", err)
+	}
+	for backend, want := range map[string]string{"sqlite": "Call Acme", "csv": "Call Globex"} {
+		if got := titles[trackedTaskKey(backend, task.UID)].Title; got != want {
+			t.Errorf("title of %s/%s = %q, want %q", backend, task.UID, got, want)
+		}
+	}
+}
+
+func TestTrackedTimeByBackend(t *testing.T) {
+	useTestWorkspace(t)
+	sqlite, _ := getStore("sqlite")
+	task, err := sqlite.Add(models.Task{Title: "Call Acme", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	started := time.Now().UTC().Add(-time.Hour)
+	for backend, minutes := range map[string]int{"sqlite": 20, "csv": 45} {
+		ended := started.Add(time.Duration(minutes) * time.Minute)
+		if _, err := database.GetDB().Exec(`INSERT INTO time_entries (backend, task_id, task_uid, started_at, ended_at) VALUES (?, ?, ?, ?, ?)`,
+			backend, task.ID, task.UID, started, ended); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// show and list count the time of the task of their backend only.
+	entries, err := getTaskTimeEntries("sqlite", task)
+	if err != nil {
+		t.Fatalf("getTaskTimeEntries: %v", err)
+	}
+	totals, err := getTrackedTotals(database.GetDB(), "sqlite")
+	if err != nil {
+		t.Fatalf("getTrackedTotals: %v", err)
+	}
+	if shown, listed := totalTracked(entries), totals[task.UID]; shown != 20*time.Minute || listed != shown {
+		t.Errorf("show tracked %v and list %v, want 20m", shown, listed)
+	}
+}
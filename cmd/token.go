@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/database"
+)
+
+const tokenPrefix = "tcli_"
+
+// apiToken is a token clients authenticate to the server with. Only the
+// SHA-256 hash of the secret is stored.
+type apiToken struct {
+	ID         int
+	Name       string
+	Prefix     string
+	Scope      string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// canWrite reports whether the token may change tasks.
+func (t apiToken) canWrite() bool {
+	return t.Scope == "write"
+}
+
+// tokenCmd represents the token command
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the API tokens of the server",
+	Long: `Manage the tokens clients of the serve command authenticate with, sent as
+"Authorization: Bearer <token>". Read tokens can only list and get tasks,
+write tokens can also create, update and delete them.`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API token",
+	Long: `Create an API token. The name is logged with the requests of the token and
+must not be taken by another active token.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scope, _ := cmd.Flags().GetString("scope")
+		token, err := createToken(args[0], scope)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s Created %s token %q. Copy it now, it cannot be shown again:\n", promptui.IconGood, scope, args[0])
+		fmt.Println(token)
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the API tokens",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tokens, err := queryTokens(`ORDER BY id`)
+		if err != nil {
+			fmt.Printf("%s Failed to fetch tokens: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No API tokens, create one with the token create command.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTOKEN\tSCOPE\tCREATED AT\tLAST USED\tSTATUS")
+		for _, token := range tokens {
+			lastUsed, status := "never", "active"
+			if token.LastUsedAt != nil {
+				lastUsed = token.LastUsedAt.Local().Format("2006-01-02 15:04")
+			}
+			if token.RevokedAt != nil {
+				status = "revoked " + token.RevokedAt.Local().Format("2006-01-02")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\n", token.ID, token.Name, token.Prefix, token.Scope,
+				token.CreatedAt.Local().Format("2006-01-02 15:04"), lastUsed, status)
+		}
+		w.Flush()
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id|name|prefix>",
+	Short: "Revoke an API token",
+	Long: `Revoke an active API token by the ID, name or token prefix shown by
+token list.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := revokeToken(args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Token %d %q revoked\n", promptui.IconGood, token.ID, token.Name)
+	},
+}
+
+func init() {
+	tokenCreateCmd.Flags().String("scope", "read", "Token scope: read, write")
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+// createToken stores a new token with the given name and scope and returns
+// its secret.
+func createToken(name string, scope string) (string, error) {
+	if scope != "read" && scope != "write" {
+		return "", fmt.Errorf("invalid scope %q, valid options are 'read' and 'write'", scope)
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return "", fmt.Errorf("the token name must not be empty")
+	}
+	if existing, err := queryTokens(`WHERE name = ? AND revoked_at IS NULL`, name); err != nil {
+		return "", fmt.Errorf("failed to fetch tokens: %v", err)
+	} else if len(existing) > 0 {
+		return "", fmt.Errorf("an active token is already named %q, revoke it or choose another name", name)
+	}
+
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate a token: %v", err)
+	}
+	token := tokenPrefix + hex.EncodeToString(secret)
+
+	_, err := database.GetDB().Exec(`INSERT INTO api_tokens (name, token_hash, prefix, scope, created_at) VALUES (?, ?, ?, ?, ?)`,
+		name, hashToken(token), token[:len(tokenPrefix)+8], scope, time.Now().UTC())
+	if err != nil {
+		return "", fmt.Errorf("failed to store the token: %v", err)
+	}
+	return token, nil
+}
+
+// revokeToken revokes the active token with the ID, name or prefix ref. A
+// prefix matching several tokens is refused.
+func revokeToken(ref string) (apiToken, error) {
+	where, args := `WHERE revoked_at IS NULL AND (name = ? OR prefix = ?)`, []interface{}{ref, strings.TrimSuffix(ref, "…")}
+	if id, err := strconv.Atoi(ref); err == nil {
+		where, args = `WHERE revoked_at IS NULL AND id = ?`, []interface{}{id}
+	}
+	tokens, err := queryTokens(where, args...)
+	if err != nil {
+		return apiToken{}, fmt.Errorf("failed to fetch tokens: %v", err)
+	}
+	switch {
+	case len(tokens) == 0:
+		return apiToken{}, fmt.Errorf("no active token %s", ref)
+	case len(tokens) > 1:
+		return apiToken{}, fmt.Errorf("%s matches %d tokens, revoke it by ID", ref, len(tokens))
+	}
+
+	if _, err := database.GetDB().Exec(`UPDATE api_tokens SET revoked_at = ? WHERE id = ?`, time.Now().UTC(), tokens[0].ID); err != nil {
+		return apiToken{}, fmt.Errorf("failed to revoke the token: %v", err)
+	}
+	return tokens[0], nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateToken returns the active token with the given secret and
+// records its use, or nil when there is none.
+func authenticateToken(secret string) (*apiToken, error) {
+	tokens, err := queryTokens(`WHERE token_hash = ? AND revoked_at IS NULL`, hashToken(secret))
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if _, err := database.GetDB().Exec(`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now, tokens[0].ID); err != nil {
+		return nil, err
+	}
+	tokens[0].LastUsedAt = &now
+	return &tokens[0], nil
+}
+
+func queryTokens(where string, args ...interface{}) ([]apiToken, error) {
+	rows, err := database.GetDB().Query(`SELECT id, name, prefix, scope, created_at, last_used_at, revoked_at FROM api_tokens `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []apiToken
+	for rows.Next() {
+		var token apiToken
+		var lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&token.ID, &token.Name, &token.Prefix, &token.Scope, &token.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, err
+		}
+		if lastUsedAt.Valid {
+			token.LastUsedAt = &lastUsedAt.Time
+		}
+		if revokedAt.Valid {
+			token.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"database/sql"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/unf6/testing/pkg/database"
+)
+
+func TestTokenNames(t *testing.T) {
+	useTestWorkspace(t)
+
+	first, err := createToken("ci", "read")
+	if err != nil {
+		t.Fatalf("createToken: %v", err)
+	}
+	if _, err := createToken("ci", "write"); err == nil {
+		t.Fatalf("createToken accepted the name of an active token")
+	}
+	if _, err := createToken("deploy", "admin"); err == nil {
+		t.Errorf("createToken accepted the admin scope")
+	}
+
+	// A prefix shared by several tokens is refused, as listed or not.
+	second, _ := createToken("deploy", "write")
+	database.GetDB().Exec(`UPDATE api_tokens SET prefix = 'tcli_shared'`)
+	for _, ref := range []string{"tcli_shared", "tcli_shared…"} {
+		if _, err := revokeToken(ref); err == nil || !strings.Contains(err.Error(), "matches 2 tokens") {
+			t.Errorf("revokeToken(%q) = %v, want the prefix refused", ref, err)
+		}
+	}
+
+	revoked, err := revokeToken("ci")
+	if err != nil || revoked.Name != "ci" {
+		t.Fatalf("revokeToken by name = %+v, %v", revoked, err)
+	}
+	if token, _ := authenticateToken(first); token != nil {
+		t.Errorf("the revoked token still authenticates")
+	}
+	if _, err := revokeToken("ci"); err == nil {
+		t.Errorf("revokeToken revoked the ci token twice")
+	}
+
+	// The name of a revoked token can be used again.
+	if _, err := createToken("ci", "read"); err != nil {
+		t.Errorf("createToken with the name of a revoked token: %v", err)
+	}
+	if token, _ := authenticateToken(second); token == nil || token.Name != "deploy" {
+		t.Errorf("the deploy token was revoked with the ci token: %+v", token)
+	}
+}
+
+func TestTokenNamesMigration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A database of a version allowing several active tokens with the same name.
+	_, err = db.Exec(`
+	CREATE TABLE api_tokens (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, token_hash TEXT NOT NULL UNIQUE,
+		prefix TEXT NOT NULL, scope TEXT NOT NULL, created_at DATETIME NOT NULL, last_used_at DATETIME, revoked_at DATETIME);
+	INSERT INTO api_tokens (name, token_hash, prefix, scope, created_at, revoked_at) VALUES
+		('ci', 'a', 'tcli_a', 'read', '2024-01-01', NULL),
+		('ci', 'b', 'tcli_b', 'read', '2024-01-01', '2024-02-01'),
+		('ci', 'c', 'tcli_c', 'read', '2024-01-01', NULL);
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = database.Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	var names []string
+	rows, _ := db.Query(`SELECT name FROM api_tokens ORDER BY id`)
+	for rows.Next() {
+		var name string
+		rows.Scan(&name)
+		names = append(names, name)
+	}
+	rows.Close()
+	if strings.Join(names, " ") != "ci ci ci-3" {
+		t.Errorf("token names = %v, want the second active ci token renamed", names)
+	}
+}
+
+func TestAPITokenScopes(t *testing.T) {
+	useTestWorkspace(t)
+	previous := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	handler := newAPIServer(newSQLiteStore(), false, &sync.Mutex{}).Handler()
+
+	read, _ := createToken("dashboard", "read")
+	write, _ := createToken("ci", "write")
+	revoked, _ := createToken("old", "write")
+	revokeToken("old")
+
+	tests := []struct {
+		method string
+		target string
+		token  string
+		status int
+	}{
+		{http.MethodGet, "/api/tasks", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/tasks", "tcli_unknown", http.StatusUnauthorized},
+		{http.MethodGet, "/api/tasks", revoked, http.StatusUnauthorized},
+		{http.MethodGet, "/api/tasks", read, http.StatusOK},
+		{http.MethodPost, "/api/tasks", read, http.StatusForbidden},
+		{http.MethodPatch, "/api/tasks/1", read, http.StatusForbidden},
+		{http.MethodDelete, "/api/tasks/1", read, http.StatusForbidden},
+		{http.MethodPost, "/api/tasks", write, http.StatusCreated},
+		{http.MethodPatch, "/api/tasks/1", write, http.StatusOK},
+		{http.MethodGet, "/api/tasks/1", write, http.StatusOK},
+		{http.MethodDelete, "/api/tasks/1", write, http.StatusNoContent},
+		// The web UI asks for a token itself.
+		{http.MethodGet, "/", "", http.StatusOK},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.target, strings.NewReader(`{"title": "Call Acme"}`))
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		response := httptest.NewRecorder()
+		handler.ServeHTTP(response, request)
+		if response.Code != test.status {
+			t.Errorf("%s %s with token %.13q = %d %s, want %d", test.method, test.target, test.token, response.Code, response.Body, test.status)
+		}
+		if response.Code == http.StatusUnauthorized && response.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s %s with token %.13q: no WWW-Authenticate header", test.method, test.target, test.token)
+		}
+	}
+
+	// The token of a change is recorded as its author.
+	var actor string
+	if err := database.GetDB().QueryRow(`SELECT actor FROM task_history ORDER BY id DESC LIMIT 1`).Scan(&actor); err != nil || actor != "token:ci" {
+		t.Errorf("history actor = %q, %v; want token:ci", actor, err)
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+)
+
+// transferCmd represents the transfer command
+var transferCmd = &cobra.Command{
+	Use:   "transfer --from [csv|sqlite] --to [csv|sqlite] [filter]",
+	Short: "Copy or move tasks between the CSV file and the database",
+	Long: `Copy tasks from one backend to the other, keeping their status and timestamps.
+An optional filter only transfers tasks whose title or description contains it.
+Tasks whose ID is already taken in the destination get a new ID, the mapping
+from old to new IDs is printed once the transfer is done. Copies get a new
+UID, while moved tasks keep theirs.
+
+With --move, the tasks are only removed from the source once all of them
+were added to the destination. When a task cannot be removed, the others
+still are and the ones left in both backends are reported.`,
+	Args:        cobra.MaximumNArgs(1),
+	Annotations: writeCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		to, _ := cmd.Flags().GetString("to")
+		status, _ := cmd.Flags().GetString("status")
+		move, _ := cmd.Flags().GetBool("move")
+
+		var filter string
+		if len(args) > 0 {
+			filter = args[0]
+		}
+
+		source, err := getStore(from)
+		if err != nil {
+			fmt.Printf("%s Error: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		destination, err := getStore(to)
+		if err != nil {
+			fmt.Printf("%s Error: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if source.Name() == destination.Name() {
+			fmt.Printf("%s Source and destination must be different backends\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		tasks, err := source.List()
+		if err != nil {
+			fmt.Printf("%s Failed to read tasks from %s: %v\n", promptui.IconBad, source.Name(), err)
+			os.Exit(1)
+		}
+
+		selected := filterTransferTasks(tasks, filter, status)
+		if len(selected) == 0 {
+			fmt.Println("No tasks found to transfer.")
+			return
+		}
+
+		transferred, err := transferTasks(source, destination, selected, move)
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "OLD ID\t\tNEW ID\tTITLE")
+		removed := 0
+		for _, task := range transferred {
+			fmt.Fprintf(w, "%d\t→\t%d\t%s\n", task.OldID, task.NewID, task.Title)
+			if task.Removed {
+				removed++
+			}
+		}
+		w.Flush()
+
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			fmt.Printf("%s %d of %d task(s) added to %s, %d removed from %s\n", promptui.IconWarn,
+				len(transferred), len(selected), destination.Name(), removed, source.Name())
+			os.Exit(1)
+		}
+
+		action := "copied"
+		if move {
+			action = "moved"
+		}
+		fmt.Printf("%s %d task(s) %s from %s to %s\n", promptui.IconGood, len(selected), action, source.Name(), destination.Name())
+	},
+}
+
+func init() {
+	transferCmd.Flags().String("from", "", "Backend to read tasks from: csv, sqlite")
+	transferCmd.Flags().String("to", "", "Backend to write tasks to: csv, sqlite")
+	transferCmd.Flags().String("status", "", "Only transfer tasks with this status")
+	transferCmd.Flags().Bool("move", false, "Remove the tasks from the source once transferred")
+	transferCmd.MarkFlagRequired("from")
+	transferCmd.MarkFlagRequired("to")
+
+	backends := func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"csv", "sqlite"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	transferCmd.RegisterFlagCompletionFunc("from", backends)
+	transferCmd.RegisterFlagCompletionFunc("to", backends)
+	rootCmd.AddCommand(transferCmd)
+}
+
+// filterTransferTasks keeps the tasks matching the text filter and status, both optional.
+func filterTransferTasks(tasks []models.Task, filter string, status string) []models.Task {
+	filter = strings.ToLower(filter)
+
+	var selected []models.Task
+	for _, task := range tasks {
+		if status != "" && task.Status != status {
+			continue
+		}
+		if filter != "" &&
+			!strings.Contains(strings.ToLower(task.Title), filter) &&
+			!strings.Contains(strings.ToLower(task.Description), filter) {
+			continue
+		}
+		selected = append(selected, task)
+	}
+	return selected
+}
+
+// transferredTask is a task added to the destination of a transfer.
+type transferredTask struct {
+	OldID int
+	NewID int
+	Title string
+	// Removed reports whether the task was removed from the source.
+	Removed bool
+}
+
+// transferTasks adds the tasks to destination, then removes them from
+// source when move is set. Nothing is removed unless every task was added.
+// It returns the tasks added, along the errors met.
+func transferTasks(source taskStore, destination taskStore, selected []models.Task, move bool) ([]transferredTask, error) {
+	var transferred []transferredTask
+	for _, task := range selected {
+		if !move {
+			// A copy is another task than the one it is copied from.
+			task.UID = ""
+		}
+		stored, err := destination.Add(task)
+		if err != nil {
+			return transferred, fmt.Errorf("failed to transfer task %d: %v", task.ID, err)
+		}
+		transferred = append(transferred, transferredTask{OldID: task.ID, NewID: stored.ID, Title: task.Title})
+	}
+	if !move {
+		return transferred, nil
+	}
+
+	var errs []error
+	for i := range transferred {
+		if err := source.Delete(transferred[i].OldID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove task %d from %s: %v", transferred[i].OldID, source.Name(), err))
+			continue
+		}
+		transferred[i].Removed = true
+	}
+	return transferred, errors.Join(errs...)
+}
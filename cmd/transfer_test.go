@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/unf6/testing/models"
+)
+
+func TestFilterTransferTasks(t *testing.T) {
+	all := []models.Task{
+		{ID: 1, Title: "Call Acme", Status: "pending"},
+		{ID: 2, Title: "Invoice", Description: "For ACME", Status: "completed"},
+		{ID: 3, Title: "Write report", Status: "pending"},
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		status string
+		want   []int
+	}{
+		{name: "all", want: []int{1, 2, 3}},
+		{name: "title or description, any case", filter: "acme", want: []int{1, 2}},
+		{name: "status", status: "pending", want: []int{1, 3}},
+		{name: "filter and status", filter: "acme", status: "completed", want: []int{2}},
+		{name: "no match", filter: "meeting", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, task := range filterTransferTasks(all, tt.filter, tt.status) {
+				got = append(got, task.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterTransferTasks(%q, %q) = %v, want %v", tt.filter, tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferTasks(t *testing.T) {
+	tests := []struct {
+		name string
+		move bool
+		// left is the titles left in the source.
+		left string
+	}{
+		{name: "copy", left: "Call Acme,Invoice"},
+		{name: "move", move: true, left: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestWorkspace(t)
+			source, _ := getStore("sqlite")
+			destination, _ := getStore("csv")
+
+			now := time.Now().UTC()
+			var selected []models.Task
+			for _, title := range []string{"Call Acme", "Invoice"} {
+				task, err := source.Add(models.Task{Title: title, Status: "pending", CreatedAt: now, UpdatedAt: now})
+				if err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+				selected = append(selected, task)
+			}
+			// The ID 1 is taken in the destination.
+			if _, err := destination.Add(models.Task{Title: "Existing", Status: "pending", CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+
+			transferred, err := transferTasks(source, destination, selected, tt.move)
+			if err != nil {
+				t.Fatalf("transferTasks: %v", err)
+			}
+			want := []transferredTask{
+				{OldID: 1, NewID: 2, Title: "Call Acme", Removed: tt.move},
+				{OldID: 2, NewID: 3, Title: "Invoice", Removed: tt.move},
+			}
+			if !reflect.DeepEqual(transferred, want) {
+				t.Errorf("transferred = %+v, want %+v", transferred, want)
+			}
+
+			if got := storedTitles(t, source); got != tt.left {
+				t.Errorf("source titles = %q, want %q", got, tt.left)
+			}
+			if got := storedTitles(t, destination); got != "Existing,Call Acme,Invoice" {
+				t.Errorf("destination titles = %q", got)
+			}
+
+			stored, err := destination.List()
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			for i, task := range selected {
+				if same := stored[i+1].UID == task.UID; same != tt.move {
+					t.Errorf("task %d kept its UID: %v, want %v", task.ID, same, tt.move)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// trashCmd represents the trash command
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Manage deleted tasks",
+	Long:  `List, restore or permanently remove the tasks moved to the trash by the delete command.`,
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tasks in the trash",
+	Run: func(cmd *cobra.Command, args []string) {
+		format := outputFormat(cmd)
+		store := promptStore("Which database should we list the trash from?")
+
+		tasks, err := store.Trashed()
+		if err != nil {
+			fmt.Printf("%s Failed to fetch the trash: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		if format == "json" {
+			formatInJSON(getDisplayData(tasks))
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUID\tTITLE\tSTATUS\tDELETED")
+		for _, task := range getDisplayData(tasks) {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", task.ID, task.UID, task.Title, task.Status, task.DeletedAt)
+		}
+		w.Flush()
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:         "restore <id>",
+	Short:       "Restore a task from the trash",
+	Args:        cobra.ExactArgs(1),
+	Annotations: writeCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		store := promptStore("Where would you like to restore from?")
+
+		tasks, err := store.Trashed()
+		if err != nil {
+			fmt.Printf("%s Failed to fetch the trash: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		task, err := matchTask(tasks, args[0])
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		task.DeletedAt = nil
+		if err := store.Update(task); err != nil {
+			fmt.Printf("%s Failed to restore task %d: %v\n", promptui.IconBad, task.ID, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Task with ID %d restored\n", promptui.IconGood, task.ID)
+	},
+}
+
+var trashPurgeCmd = &cobra.Command{
+	Use:         "purge",
+	Short:       "Permanently remove the tasks in the trash",
+	Annotations: writeCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		olderThan, _ := cmd.Flags().GetString("older-than")
+
+		var age time.Duration
+		if olderThan != "" {
+			var err error
+			if age, err = utils.ParseDuration(olderThan); err != nil {
+				fmt.Printf("%s %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+		}
+
+		store := promptStore("Which trash should be purged?")
+		tasks, err := store.Trashed()
+		if err != nil {
+			fmt.Printf("%s Failed to fetch the trash: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		purged := 0
+		for _, task := range tasks {
+			if time.Since(*task.DeletedAt) < age {
+				continue
+			}
+			if err := store.Delete(task.ID); err != nil {
+				fmt.Printf("%s Failed to purge task %d: %v\n", promptui.IconBad, task.ID, err)
+				os.Exit(1)
+			}
+			purged++
+		}
+		fmt.Printf("%s Purged %d task(s) from the trash\n", promptui.IconGood, purged)
+	},
+}
+
+func init() {
+	trashListCmd.Flags().StringP("format", "f", "table", "Output format: table, json")
+	trashPurgeCmd.Flags().String("older-than", "", "Only purge tasks deleted longer ago than this, e.g. 30d or 12h")
+
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashPurgeCmd)
+	rootCmd.AddCommand(trashCmd)
+}
+
+// promptStore asks which backend to use and returns its store. Nothing is
+// asked when the config file selects a backend.
+func promptStore(label string) taskStore {
+	if store := configuredStore(); store != nil {
+		return store
+	}
+
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     []string{"Database (sqlite)", "CSV File"},
+		CursorPos: 0,
+	}
+
+	_, choice, err := prompt.Run()
+	if err != nil {
+		fmt.Printf("%s Error: %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+
+	if choice == "CSV File" {
+		return newCSVStore()
+	}
+	return newSQLiteStore()
+}
@@ -0,0 +1,402 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+)
+
+// boardMode is what keystrokes currently do on the board.
+type boardMode int
+
+const (
+	modeBrowse boardMode = iota
+	modeFilter
+	modeEdit
+	modeCreate
+)
+
+// board is the state of the kanban board of the tui command.
+type board struct {
+	store   taskStore
+	tasks   []models.Task
+	columns []string
+	col     int
+	row     int
+	filter  string
+	mode    boardMode
+	input   []rune
+	message string
+	// offsets are the first card shown of each column by status, when a
+	// column has more cards than fit on the screen.
+	offsets map[string]int
+}
+
+// tuiCmd represents the tui command
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Open a full-screen kanban board",
+	Long: `Open a kanban board with one column per status.
+
+  ←/→ h/l   select column       ↑/↓ k/j   select task
+  < >       move task to the previous/next column
+  e         edit the title      n         create a task in the column
+  d         move to the trash   /         filter by title or description
+  r         reload              q         quit`,
+	Args:        cobra.NoArgs,
+	Annotations: writeCommand,
+	Run: func(cmd *cobra.Command, args []string) {
+		fd := int(os.Stdin.Fd())
+		if !readline.IsTerminal(fd) {
+			fmt.Printf("%s The board needs an interactive terminal\n", promptui.IconBad)
+			os.Exit(1)
+		}
+
+		b := &board{store: promptStore("Which database should the board show?")}
+		if err := b.reload(); err != nil {
+			fmt.Printf("%s Failed to fetch tasks: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		state, err := readline.MakeRaw(fd)
+		if err != nil {
+			fmt.Printf("%s Failed to set up the terminal: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		// Alternate screen, hidden cursor
+		fmt.Print("\x1b[?1049h\x1b[?25l")
+		defer func() {
+			fmt.Print("\x1b[?25h\x1b[?1049l")
+			readline.Restore(fd, state)
+		}()
+
+		buf := make([]byte, 64)
+		for {
+			b.draw()
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				return
+			}
+			if !b.handleKey(string(buf[:n])) {
+				return
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+}
+
+// reload reads the tasks again and lays out the columns.
+func (b *board) reload() error {
+	tasks, err := b.store.List()
+	if err != nil {
+		return err
+	}
+	b.tasks = tasks
+
+	b.columns = append([]string{}, taskStatuses...)
+	for _, task := range tasks {
+		if !containsString(b.columns, task.Status) {
+			b.columns = append(b.columns, task.Status)
+		}
+	}
+	b.clampCursor()
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// cards returns the tasks of a column matching the filter.
+func (b *board) cards(col int) []models.Task {
+	filter := strings.ToLower(b.filter)
+	var cards []models.Task
+	for _, task := range b.tasks {
+		if task.Status != b.columns[col] {
+			continue
+		}
+		if filter != "" &&
+			!strings.Contains(strings.ToLower(task.Title), filter) &&
+			!strings.Contains(strings.ToLower(task.Description), filter) {
+			continue
+		}
+		cards = append(cards, task)
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	return cards
+}
+
+// selected returns the task under the cursor.
+func (b *board) selected() (models.Task, bool) {
+	cards := b.cards(b.col)
+	if b.row < len(cards) {
+		return cards[b.row], true
+	}
+	return models.Task{}, false
+}
+
+func (b *board) clampCursor() {
+	if b.col >= len(b.columns) {
+		b.col = len(b.columns) - 1
+	}
+	if b.col < 0 {
+		b.col = 0
+	}
+	if count := len(b.cards(b.col)); b.row >= count {
+		b.row = count - 1
+	}
+	if b.row < 0 {
+		b.row = 0
+	}
+}
+
+// handleKey applies a keystroke and reports whether the board stays open.
+func (b *board) handleKey(key string) bool {
+	if b.mode != modeBrowse {
+		b.handleInput(key)
+		return true
+	}
+
+	b.message = ""
+	switch key {
+	case "q", "\x03":
+		return false
+	case "\x1b[D", "h":
+		b.col--
+	case "\x1b[C", "l":
+		b.col++
+	case "\x1b[A", "k":
+		b.row--
+	case "\x1b[B", "j":
+		b.row++
+	case "<", ",":
+		b.moveSelected(-1)
+	case ">", ".":
+		b.moveSelected(1)
+	case "e":
+		if task, ok := b.selected(); ok {
+			b.mode, b.input = modeEdit, []rune(task.Title)
+		}
+	case "n":
+		b.mode, b.input = modeCreate, nil
+	case "/":
+		b.mode, b.input = modeFilter, []rune(b.filter)
+	case "d":
+		if task, ok := b.selected(); ok {
+			b.apply(fmt.Sprintf("Task %d moved to the trash", task.ID), trashTask(b.store, task))
+		}
+	case "r":
+		b.apply("Reloaded", nil)
+	}
+	b.clampCursor()
+	return true
+}
+
+// handleInput edits the text of the filter, edit and create prompts.
+func (b *board) handleInput(key string) {
+	switch key {
+	case "\x1b", "\x03":
+		b.mode = modeBrowse
+	case "\r", "\n":
+		text := strings.TrimSpace(string(b.input))
+		mode := b.mode
+		b.mode = modeBrowse
+		switch mode {
+		case modeFilter:
+			b.filter = text
+			b.row = 0
+		case modeEdit:
+			if task, ok := b.selected(); ok && text != "" {
+				task.Title = text
+				task.UpdatedAt = time.Now().UTC()
+				b.apply(fmt.Sprintf("Task %d renamed", task.ID), b.store.Update(task))
+			}
+		case modeCreate:
+			if text != "" {
+				now := time.Now().UTC()
+				_, err := b.store.Add(models.Task{Title: text, Status: b.columns[b.col], CreatedAt: now, UpdatedAt: now})
+				b.apply("Task created", err)
+			}
+		}
+	case "\x7f", "\b":
+		if len(b.input) > 0 {
+			b.input = b.input[:len(b.input)-1]
+		}
+	default:
+		if !strings.HasPrefix(key, "\x1b") {
+			for _, r := range key {
+				if r >= ' ' {
+					b.input = append(b.input, r)
+				}
+			}
+		}
+	}
+	b.clampCursor()
+}
+
+// moveSelected moves the selected task to the column at offset from its own.
+func (b *board) moveSelected(offset int) {
+	task, ok := b.selected()
+	target := b.col + offset
+	if !ok || target < 0 || target >= len(b.columns) {
+		return
+	}
+
+	task.Status = b.columns[target]
+	task.UpdatedAt = time.Now().UTC()
+	b.apply(fmt.Sprintf("Task %d moved to %s", task.ID, task.Status), b.store.Update(task))
+
+	b.col = target
+	for i, card := range b.cards(target) {
+		if card.ID == task.ID {
+			b.row = i
+		}
+	}
+}
+
+// apply reports the outcome of a change and reloads the tasks. Every change
+// is journaled as its own operation so undo reverts them one at a time.
+func (b *board) apply(message string, err error) {
+	operationID = 0
+	if err != nil {
+		b.message = "Error: " + err.Error()
+		return
+	}
+	if err := b.reload(); err != nil {
+		b.message = "Error: " + err.Error()
+		return
+	}
+	b.message = message
+}
+
+func (b *board) draw() {
+	width, height, err := readline.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width, height = 80, 24
+	}
+	colWidth := width / len(b.columns)
+
+	var out strings.Builder
+	out.WriteString("\x1b[H\x1b[2J")
+
+	title := fmt.Sprintf(" tasks-cli — %s", b.store.Name())
+	if b.filter != "" {
+		title += fmt.Sprintf("  filter: %q", b.filter)
+	}
+	out.WriteString("\x1b[1m" + fitText(title, width) + "\x1b[0m\r\n\r\n")
+
+	visible := height - 6
+	if visible < 1 {
+		visible = 1
+	}
+	columns := make([][]models.Task, len(b.columns))
+	offsets := make([]int, len(b.columns))
+	rows := 0
+	for i, status := range b.columns {
+		columns[i] = b.cards(i)
+		offsets[i] = b.scroll(i, len(columns[i]), visible)
+		header := fitText(fmt.Sprintf(" %s (%d)", strings.ToUpper(status), len(columns[i])), colWidth)
+		if i == b.col {
+			header = "\x1b[1;4m" + header + "\x1b[0m"
+		}
+		out.WriteString(header)
+		if shown := len(columns[i]) - offsets[i]; shown > rows {
+			rows = shown
+		}
+	}
+	out.WriteString("\r\n")
+
+	if rows > visible {
+		rows = visible
+	}
+	for r := 0; r < rows; r++ {
+		for c := range b.columns {
+			cell := ""
+			card := offsets[c] + r
+			if card < len(columns[c]) {
+				cell = fmt.Sprintf(" #%d %s", columns[c][card].ID, columns[c][card].Title)
+			}
+			cell = fitText(cell, colWidth-1) + " "
+			if c == b.col && card == b.row && card < len(columns[c]) {
+				cell = "\x1b[7m" + cell + "\x1b[0m"
+			}
+			out.WriteString(cell)
+		}
+		out.WriteString("\r\n")
+	}
+
+	out.WriteString(fmt.Sprintf("\x1b[%d;1H", height-1))
+	switch b.mode {
+	case modeFilter:
+		out.WriteString("Filter: " + string(b.input) + "█")
+	case modeEdit:
+		out.WriteString("Title: " + string(b.input) + "█")
+	case modeCreate:
+		out.WriteString(fmt.Sprintf("New %s task: %s█", b.columns[b.col], string(b.input)))
+	default:
+		out.WriteString(fitText(b.message, width))
+	}
+	out.WriteString(fmt.Sprintf("\x1b[%d;1H\x1b[2m", height))
+	out.WriteString(fitText("←→↑↓ select  <> move  e edit  n new  d trash  / filter  r reload  q quit", width))
+	out.WriteString("\x1b[0m")
+
+	fmt.Print(out.String())
+}
+
+// scroll returns the first card of a column shown when visible cards fit on
+// the screen, scrolling the selected column so the cursor stays visible.
+func (b *board) scroll(col int, count int, visible int) int {
+	if b.offsets == nil {
+		b.offsets = map[string]int{}
+	}
+	status := b.columns[col]
+	offset := b.offsets[status]
+	if col == b.col {
+		if b.row < offset {
+			offset = b.row
+		}
+		if b.row >= offset+visible {
+			offset = b.row - visible + 1
+		}
+	}
+	if offset > count-visible {
+		offset = count - visible
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	b.offsets[status] = offset
+	return offset
+}
+
+// fitText pads or cuts text to exactly width characters.
+func fitText(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	count := utf8.RuneCountInString(text)
+	if count > width {
+		runes := []rune(text)
+		if width == 1 {
+			return string(runes[:1])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-count)
+}
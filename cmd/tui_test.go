@@ -0,0 +1,35 @@
+package cmd
+
+import "testing"
+
+func TestBoardScroll(t *testing.T) {
+	b := &board{columns: []string{"pending", "completed"}}
+
+	// The cursor moves down 12 cards of 20 with 5 visible, then back up.
+	tests := []struct {
+		row  int
+		want int
+	}{
+		{0, 0}, {4, 0}, {5, 1}, {12, 8}, {10, 8}, {8, 8}, {7, 7}, {19, 15}, {0, 0},
+	}
+	for _, test := range tests {
+		b.row = test.row
+		if got := b.scroll(0, 20, 5); got != test.want {
+			t.Errorf("offset with the cursor on card %d = %d, want %d", test.row, got, test.want)
+		}
+	}
+
+	// Another column keeps its offset while it is not selected, within its cards.
+	b.col, b.row = 1, 9
+	b.scroll(1, 10, 5)
+	b.col, b.row = 0, 0
+	if got := b.scroll(1, 10, 5); got != 5 {
+		t.Errorf("offset of the unselected column = %d, want 5", got)
+	}
+	if got := b.scroll(1, 7, 5); got != 2 {
+		t.Errorf("offset once cards were removed = %d, want 2", got)
+	}
+	if got := b.scroll(1, 7, 10); got != 0 {
+		t.Errorf("offset when every card fits = %d, want 0", got)
+	}
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// journalLimit is the number of operations kept in the journal, older ones
+// can no longer be undone.
+const journalLimit = 100
+
+// errNothingToReplay is returned by replayOperation when there is no
+// operation to undo or redo.
+var errNothingToReplay = errors.New("nothing to replay")
+
+var (
+	// operationCommand names the command whose changes are being journaled.
+	operationCommand string
+	// operationID is the journal entry of the running command, created on its first change.
+	operationID int64
+	// journalPaused stops undo and redo from journaling the changes they replay.
+	journalPaused bool
+)
+
+// journalChange is one task change of an operation: before is nil for a
+// created task and after is nil for a deleted one.
+type journalChange struct {
+	Backend string
+	Before  *models.Task
+	After   *models.Task
+}
+
+// undoCmd represents the undo command
+var undoCmd = &cobra.Command{
+	Use:    "undo",
+	Short:  "Undo the last create, edit, delete, import or transfer",
+	PreRun: requireLocalBackend,
+	Run: func(cmd *cobra.Command, args []string) {
+		runReplay(true)
+	},
+}
+
+// redoCmd represents the redo command
+var redoCmd = &cobra.Command{
+	Use:    "redo",
+	Short:  "Redo the last undone operation",
+	PreRun: requireLocalBackend,
+	Run: func(cmd *cobra.Command, args []string) {
+		runReplay(false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(redoCmd)
+}
+
+// journal records a task change as part of the running command. The first
+// change of a command discards the operations that could still be redone.
+func journal(backend string, before *models.Task, after *models.Task) error {
+	if journalPaused {
+		return nil
+	}
+
+	db := database.GetDB()
+	if operationID == 0 {
+		// The changes of the operations are deleted along them.
+		if _, err := db.Exec(`DELETE FROM operations WHERE undone = 1`); err != nil {
+			return fmt.Errorf("error clearing redo journal: %v", err)
+		}
+
+		result, err := db.Exec(`INSERT INTO operations (command) VALUES (?)`, operationCommand)
+		if err != nil {
+			return fmt.Errorf("error journaling operation: %v", err)
+		}
+		if operationID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("error journaling operation: %v", err)
+		}
+		if _, err := db.Exec(`DELETE FROM operations WHERE id <= ?`, operationID-journalLimit); err != nil {
+			return fmt.Errorf("error trimming the journal: %v", err)
+		}
+	}
+
+	key, err := contentKey()
+	if err != nil {
+		return err
+	}
+	beforeJSON, err := marshalJournalTask(before, key, journalLocation("before", operationID))
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalJournalTask(after, key, journalLocation("after", operationID))
+	if err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`INSERT INTO operation_changes (operation_id, backend, before, after) VALUES (?, ?, ?, ?)`,
+		operationID, backend, beforeJSON, afterJSON); err != nil {
+		return fmt.Errorf("error journaling change: %v", err)
+	}
+	return nil
+}
+
+// journalLocation returns the location of column in the changes of the
+// operation id.
+func journalLocation(column string, id int64) encryption.Location {
+	return encryption.Location{Table: "operation_changes", Column: column, Row: strconv.FormatInt(id, 10)}
+}
+
+// marshalJournalTask returns the JSON of a task, encrypted with key at at
+// unless key is nil.
+func marshalJournalTask(task *models.Task, key *encryption.Key, at encryption.Location) (interface{}, error) {
+	if task == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("error journaling change: %v", err)
+	}
+	return key.EncryptString(string(data), at), nil
+}
+
+// runReplay undoes the latest operation, or redoes the latest undone one,
+// and reports it.
+func runReplay(undo bool) {
+	command, changes, err := replayOperation(undo)
+	if errors.Is(err, errNothingToReplay) {
+		if undo {
+			fmt.Println("Nothing to undo.")
+		} else {
+			fmt.Println("Nothing to redo.")
+		}
+		return
+	}
+	if err != nil {
+		fmt.Printf("%s %v\n", promptui.IconBad, err)
+		os.Exit(1)
+	}
+
+	action := "Redid"
+	if undo {
+		action = "Undid"
+	}
+	fmt.Printf("%s %s %s (%d change(s))\n", promptui.IconGood, action, command, changes)
+}
+
+// replayOperation undoes the latest operation, or redoes the latest undone
+// one, and returns its command and number of changes. Nothing is replayed
+// when one of its tasks was changed since.
+func replayOperation(undo bool) (string, int, error) {
+	db := database.GetDB()
+
+	query := `SELECT id, command FROM operations WHERE undone = 1 ORDER BY id ASC LIMIT 1`
+	if undo {
+		query = `SELECT id, command FROM operations WHERE undone = 0 ORDER BY id DESC LIMIT 1`
+	}
+
+	var id int64
+	var command string
+	err := db.QueryRow(query).Scan(&id, &command)
+	if err == sql.ErrNoRows {
+		return "", 0, errNothingToReplay
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("Failed to read the operation journal: %v", err)
+	}
+
+	changes, err := getJournalChanges(id)
+	if err != nil {
+		return command, 0, fmt.Errorf("Failed to read the operation journal: %v", err)
+	}
+
+	// Undoing replays the changes backwards, from their after state to
+	// their before state.
+	steps := changes
+	if undo {
+		steps = make([]journalChange, len(changes))
+		for i, change := range changes {
+			steps[len(changes)-1-i] = journalChange{Backend: change.Backend, Before: change.After, After: change.Before}
+		}
+	}
+
+	ids, err := checkReplay(steps)
+	if err != nil {
+		return command, 0, fmt.Errorf("Cannot replay %s: %v", command, err)
+	}
+	journalPaused = true
+	err = applySteps(steps, ids)
+	journalPaused = false
+	if err != nil {
+		return command, 0, fmt.Errorf("Failed to replay %s: %v", command, err)
+	}
+
+	if _, err := db.Exec(`UPDATE operations SET undone = ? WHERE id = ?`, undo, id); err != nil {
+		return command, 0, fmt.Errorf("Failed to update the operation journal: %v", err)
+	}
+	return command, len(changes), nil
+}
+
+// replayKey identifies the task of a step across its states.
+func replayKey(step journalChange) string {
+	task := step.Before
+	if task == nil {
+		task = step.After
+	}
+	return step.Backend + "/" + task.UID
+}
+
+// checkReplay returns an error when a task of the steps is not in the state
+// its first step starts from, as another command changed it since. It
+// returns the current ID of the existing tasks by replayKey.
+func checkReplay(steps []journalChange) (map[string]int, error) {
+	ids := make(map[string]int)
+	checked := make(map[string]bool)
+	for _, step := range steps {
+		key := replayKey(step)
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+
+		store, err := getStore(step.Backend)
+		if err != nil {
+			return nil, err
+		}
+		task := step.Before
+		if task == nil {
+			task = step.After
+		}
+		found, err := tasks.NewService(store).ByUID(context.Background(), []string{task.UID})
+		if err != nil {
+			return nil, err
+		}
+		var current *models.Task
+		if stored, ok := found[task.UID]; ok {
+			current = &stored
+			ids[key] = stored.ID
+		}
+		if !sameTaskState(current, step.Before) {
+			return nil, fmt.Errorf("task %d of the %s backend was changed since", task.ID, step.Backend)
+		}
+	}
+	return ids, nil
+}
+
+// sameTaskState reports whether two states of a task match, nil standing
+// for a task that does not exist.
+func sameTaskState(a *models.Task, b *models.Task) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	sameTime := func(a *time.Time, b *time.Time) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.Equal(*b)
+	}
+	return a.Title == b.Title && a.Description == b.Description && a.Status == b.Status && a.Project == b.Project &&
+		strings.Join(a.Tags, ",") == strings.Join(b.Tags, ",") && a.UpdatedAt.Equal(b.UpdatedAt) &&
+		sameTime(a.DeletedAt, b.DeletedAt) && sameTime(a.ArchivedAt, b.ArchivedAt)
+}
+
+// applySteps moves the tasks of the steps from their before state to their
+// after state, ids holding their current ID by replayKey. The changes of the
+// database are made in one transaction, committed once the ones of the CSV
+// file are made too. Their history and webhooks are recorded afterwards.
+func applySteps(steps []journalChange, ids map[string]int) error {
+	var applied []journalChange
+	record := func(backend string, before *models.Task, after *models.Task) error {
+		applied = append(applied, journalChange{Backend: backend, Before: before, After: after})
+		return nil
+	}
+
+	sqlite := &tasks.SQLiteStore{DB: database.GetDB(), OnChange: record, Keys: keyring.Key}
+	err := sqlite.InTx(func(tx *tasks.SQLiteStore) error {
+		csvStore := &tasks.CSVStore{Path: getCSVFilePath(), OnChange: record, Keys: keyring.Key}
+		for _, backend := range []taskStore{tx, csvStore} {
+			for _, step := range steps {
+				if step.Backend != backend.Key() {
+					continue
+				}
+				if err := applyStep(backend, step, ids); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// The changes of the CSV file made before the failure stay.
+		applied = slices.DeleteFunc(applied, func(change journalChange) bool { return change.Backend == sqlite.Key() })
+	}
+
+	for _, change := range applied {
+		if recordErr := recordChange(change.Backend, change.Before, change.After); recordErr != nil && err == nil {
+			err = recordErr
+		}
+	}
+	return err
+}
+
+// applyStep moves a task of the store from the before state of step to its
+// after state.
+func applyStep(store taskStore, step journalChange, ids map[string]int) error {
+	key := replayKey(step)
+	switch {
+	case step.Before == nil:
+		added, err := store.Add(*step.After)
+		ids[key] = added.ID
+		return err
+	case step.After == nil:
+		return store.Delete(ids[key])
+	}
+	task := *step.After
+	task.ID = ids[key]
+	return store.Update(task)
+}
+
+func getJournalChanges(operationID int64) ([]journalChange, error) {
+	key, err := contentKey()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.GetDB().Query(`SELECT backend, before, after FROM operation_changes WHERE operation_id = ? ORDER BY id`, operationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []journalChange
+	for rows.Next() {
+		var change journalChange
+		var before, after sql.NullString
+		if err := rows.Scan(&change.Backend, &before, &after); err != nil {
+			return nil, err
+		}
+		if change.Before, err = unmarshalJournalTask(before, key, journalLocation("before", operationID)); err != nil {
+			return nil, err
+		}
+		if change.After, err = unmarshalJournalTask(after, key, journalLocation("after", operationID)); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// unmarshalJournalTask reads a task marshaled with marshalJournalTask.
+func unmarshalJournalTask(value sql.NullString, key *encryption.Key, at encryption.Location) (*models.Task, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	data, err := key.DecryptString(value.String, at)
+	if err != nil {
+		return nil, err
+	}
+	task := &models.Task{}
+	if err := json.Unmarshal([]byte(data), task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// runTestCommand makes the changes of run as one journaled command.
+func runTestCommand(t *testing.T, command string, run func() error) {
+	t.Helper()
+	operationCommand, operationID = command, 0
+	if err := run(); err != nil {
+		t.Fatalf("%s: %v", command, err)
+	}
+	operationID = 0
+}
+
+// storedTitles returns the titles of the tasks of the store, trashed and
+// archived ones included.
+func storedTitles(t *testing.T, store taskStore) string {
+	t.Helper()
+	page, err := tasks.NewService(store).List(context.Background(), tasks.ListOptions{View: tasks.ViewAll})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var titles []string
+	for _, task := range page.Tasks {
+		titles = append(titles, task.Title)
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestUndoRedo(t *testing.T) {
+	for _, backend := range []string{"sqlite", "csv"} {
+		t.Run(backend, func(t *testing.T) {
+			useTestWorkspace(t)
+			store, _ := getStore(backend)
+
+			var task models.Task
+			runTestCommand(t, "create", func() (err error) {
+				task, err = store.Add(models.Task{Title: "Call Acme", Status: "pending"})
+				return err
+			})
+			runTestCommand(t, "edit", func() error {
+				task.Title = "Call Acme back"
+				return store.Update(task)
+			})
+			runTestCommand(t, "delete", func() error {
+				return store.Delete(task.ID)
+			})
+
+			steps := []struct {
+				undo bool
+				want string
+			}{
+				{true, "Call Acme back"},
+				{true, "Call Acme"},
+				{true, ""},
+				{false, "Call Acme"},
+				{false, "Call Acme back"},
+				{false, ""},
+			}
+			for _, step := range steps {
+				if _, _, err := replayOperation(step.undo); err != nil {
+					t.Fatalf("replayOperation(%v): %v", step.undo, err)
+				}
+				if got := storedTitles(t, store); got != step.want {
+					t.Errorf("tasks after replayOperation(%v) = %q, want %q", step.undo, got, step.want)
+				}
+			}
+			if _, _, err := replayOperation(false); !errors.Is(err, errNothingToReplay) {
+				t.Errorf("redo of the last operation: %v, want errNothingToReplay", err)
+			}
+		})
+	}
+}
+
+func TestUndoConflict(t *testing.T) {
+	for _, backend := range []string{"sqlite", "csv"} {
+		t.Run(backend, func(t *testing.T) {
+			useTestWorkspace(t)
+			store, _ := getStore(backend)
+
+			var task models.Task
+			runTestCommand(t, "create", func() (err error) {
+				task, err = store.Add(models.Task{Title: "Call Acme", Status: "pending"})
+				return err
+			})
+			runTestCommand(t, "edit", func() error {
+				task.Title = "Call Acme back"
+				return store.Update(task)
+			})
+
+			// A change left out of the journal, as auto-archiving makes.
+			journalPaused = true
+			task.Status = "completed"
+			err := store.Update(task)
+			journalPaused = false
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			if _, _, err := replayOperation(true); err == nil || !strings.Contains(err.Error(), "changed since") {
+				t.Errorf("undo of a changed task: %v, want a conflict", err)
+			}
+			if got := storedTitles(t, store); got != "Call Acme back" {
+				t.Errorf("tasks after the conflict = %q, want them unchanged", got)
+			}
+		})
+	}
+}
+
+func TestJournalLimit(t *testing.T) {
+	useTestWorkspace(t)
+	store := newSQLiteStore()
+	for i := 0; i < journalLimit+5; i++ {
+		runTestCommand(t, "create", func() error {
+			_, err := store.Add(models.Task{Title: "Call Acme", Status: "pending"})
+			return err
+		})
+	}
+
+	var operations, changes int
+	database.GetDB().QueryRow(`SELECT COUNT(*) FROM operations`).Scan(&operations)
+	database.GetDB().QueryRow(`SELECT COUNT(*) FROM operation_changes`).Scan(&changes)
+	if operations != journalLimit || changes != journalLimit {
+		t.Errorf("journal holds %d operations and %d changes, want %d of each", operations, changes, journalLimit)
+	}
+}
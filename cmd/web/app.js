@@ -0,0 +1,257 @@
+// Single page UI of tasks-cli serve, it only talks to the JSON API.
+"use strict";
+
+const statuses = ["pending", "in-progress", "completed"];
+const pageSize = 50;
+
+const state = {
+  layout: "table",
+  offset: 0,
+  total: 0,
+  tasks: [],
+  editing: null,
+};
+
+const $ = (selector) => document.querySelector(selector);
+
+async function api(method, path, body) {
+  const headers = { "Content-Type": "application/json" };
+  const token = localStorage.getItem("tasks-cli-token");
+  if (token) {
+    headers.Authorization = "Bearer " + token;
+  }
+
+  const response = await fetch(path, { method, headers, body: body && JSON.stringify(body) });
+  if (response.status === 401) {
+    await askToken();
+    return api(method, path, body);
+  }
+  if (response.status === 204) {
+    return null;
+  }
+
+  const data = await response.json();
+  if (!response.ok) {
+    const error = new Error(data.error);
+    error.fields = data.fields || {};
+    throw error;
+  }
+  return data;
+}
+
+function askToken() {
+  return new Promise((resolve) => {
+    const dialog = $("#token-dialog");
+    dialog.addEventListener("close", () => {
+      localStorage.setItem("tasks-cli-token", $("#token-form").elements.token.value.trim());
+      resolve();
+    }, { once: true });
+    dialog.showModal();
+  });
+}
+
+function showMessage(text) {
+  const message = $("#message");
+  message.textContent = text;
+  message.hidden = !text;
+}
+
+function filters() {
+  const form = $("#filters");
+  return { q: form.elements.q.value, status: form.elements.status.value, view: form.elements.view.value };
+}
+
+async function load() {
+  const query = new URLSearchParams();
+  for (const [name, value] of Object.entries(filters())) {
+    if (value) {
+      query.set(name, value);
+    }
+  }
+  // The board shows every matching task, the table a page of them.
+  query.set("limit", state.layout === "board" ? 500 : pageSize);
+  query.set("offset", state.layout === "board" ? 0 : state.offset);
+
+  try {
+    const page = await api("GET", "/api/tasks?" + query);
+    state.tasks = page.tasks;
+    state.total = page.total;
+    showMessage("");
+    render();
+  } catch (error) {
+    showMessage(error.message);
+  }
+}
+
+function render() {
+  $("#table-layout").hidden = state.layout !== "table";
+  $("#board-layout").hidden = state.layout !== "board";
+  if (state.layout === "table") {
+    renderTable();
+  } else {
+    renderBoard();
+  }
+}
+
+function element(tag, props, ...children) {
+  const node = Object.assign(document.createElement(tag), props);
+  node.append(...children);
+  return node;
+}
+
+function statusBadge(status) {
+  return element("span", { className: "status " + status, textContent: status });
+}
+
+function taskActions(task) {
+  const actions = [];
+  if (task.deleted_at) {
+    actions.push(element("button", { textContent: "Restore", onclick: () => update(task, { deleted_at: null }) }));
+  } else {
+    actions.push(element("button", { textContent: "Edit", onclick: () => openForm(task) }));
+    actions.push(element("button", { textContent: "Delete", className: "danger", onclick: () => remove(task) }));
+  }
+  return actions;
+}
+
+function renderTable() {
+  const rows = state.tasks.map((task) => element("tr", {},
+    element("td", { textContent: task.id }),
+    element("td", { textContent: task.title }),
+    element("td", { className: "description", textContent: task.description }),
+    element("td", {}, statusBadge(task.status)),
+    element("td", { textContent: new Date(task.updated_at).toLocaleString() }),
+    element("td", { className: "actions" }, ...taskActions(task)),
+  ));
+  if (rows.length === 0) {
+    rows.push(element("tr", {}, element("td", { colSpan: 6, textContent: "No tasks found." })));
+  }
+  $("#rows").replaceChildren(...rows);
+
+  const last = Math.min(state.offset + pageSize, state.total);
+  $("#page-info").textContent = state.total ? `${state.offset + 1}–${last} of ${state.total}` : "";
+  $("#prev").disabled = state.offset === 0;
+  $("#next").disabled = last >= state.total;
+}
+
+function renderBoard() {
+  const columns = [...statuses];
+  for (const task of state.tasks) {
+    if (!columns.includes(task.status)) {
+      columns.push(task.status);
+    }
+  }
+
+  $("#columns").replaceChildren(...columns.map((status) => {
+    const cards = state.tasks.filter((task) => task.status === status).map((task) => {
+      const card = element("div", { className: "card", draggable: true, ondblclick: () => openForm(task) },
+        `#${task.id} ${task.title}`,
+        element("small", { textContent: task.description }),
+      );
+      card.addEventListener("dragstart", (event) => event.dataTransfer.setData("text/plain", task.id));
+      return card;
+    });
+
+    const column = element("div", { className: "column" },
+      element("h2", { textContent: `${status} (${cards.length})` }), ...cards);
+    column.addEventListener("dragover", (event) => {
+      event.preventDefault();
+      column.classList.add("over");
+    });
+    column.addEventListener("dragleave", () => column.classList.remove("over"));
+    column.addEventListener("drop", (event) => {
+      event.preventDefault();
+      column.classList.remove("over");
+      const task = state.tasks.find((task) => String(task.id) === event.dataTransfer.getData("text/plain"));
+      if (task && task.status !== status) {
+        update(task, { status });
+      }
+    });
+    return column;
+  }));
+}
+
+function openForm(task) {
+  state.editing = task;
+  const form = $("#task-form");
+  form.reset();
+  form.querySelectorAll(".error").forEach((error) => (error.textContent = ""));
+  $("#task-form-title").textContent = task ? `Edit task ${task.id}` : "New task";
+  if (task) {
+    form.elements.title.value = task.title;
+    form.elements.description.value = task.description;
+    form.elements.status.value = task.status;
+  }
+  $("#task-dialog").showModal();
+}
+
+async function saveForm(event) {
+  if (event.submitter && event.submitter.value === "cancel") {
+    return;
+  }
+  event.preventDefault();
+
+  const form = $("#task-form");
+  const fields = { title: form.elements.title.value, description: form.elements.description.value, status: form.elements.status.value };
+  try {
+    if (state.editing) {
+      await api("PATCH", `/api/tasks/${state.editing.id}`, fields);
+    } else {
+      await api("POST", "/api/tasks", fields);
+    }
+    $("#task-dialog").close();
+    load();
+  } catch (error) {
+    form.querySelectorAll(".error").forEach((node) => (node.textContent = error.fields[node.dataset.field] || ""));
+    if (Object.keys(error.fields).length === 0) {
+      showMessage(error.message);
+    }
+  }
+}
+
+async function update(task, fields) {
+  try {
+    await api("PATCH", `/api/tasks/${task.id}`, fields);
+    load();
+  } catch (error) {
+    showMessage(error.message);
+  }
+}
+
+async function remove(task) {
+  if (!confirm(`Move task ${task.id} "${task.title}" to the trash?`)) {
+    return;
+  }
+  try {
+    await api("DELETE", `/api/tasks/${task.id}`);
+    load();
+  } catch (error) {
+    showMessage(error.message);
+  }
+}
+
+document.querySelectorAll("nav button").forEach((button) => {
+  button.addEventListener("click", () => {
+    document.querySelectorAll("nav button").forEach((other) => other.classList.toggle("active", other === button));
+    state.layout = button.dataset.layout;
+    load();
+  });
+});
+
+$("#filters").addEventListener("input", () => {
+  state.offset = 0;
+  load();
+});
+$("#filters").addEventListener("submit", (event) => event.preventDefault());
+$("#prev").addEventListener("click", () => {
+  state.offset = Math.max(0, state.offset - pageSize);
+  load();
+});
+$("#next").addEventListener("click", () => {
+  state.offset += pageSize;
+  load();
+});
+$("#new-task").addEventListener("click", () => openForm(null));
+$("#task-form").addEventListener("submit", saveForm);
+
+load();
@@ -0,0 +1,522 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+const (
+	// webhookMaxAttempts is the number of deliveries tried before giving up.
+	webhookMaxAttempts = 8
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = time.Hour
+	webhookTimeout     = 5 * time.Second
+	// webhookClaim is how long a delivery is left to the process that
+	// claimed it before another one may send it.
+	webhookClaim = time.Minute
+	// webhookPollInterval is how often the server delivers the outbox.
+	webhookPollInterval = 2 * time.Second
+)
+
+var webhookEvents = []string{"created", "updated", "completed", "deleted"}
+
+// webhook is a URL notified of task events.
+type webhook struct {
+	ID        int
+	URL       string
+	Events    []string
+	Secret    string
+	CreatedAt time.Time
+}
+
+// accepts reports whether the webhook subscribed to the event.
+func (h webhook) accepts(event string) bool {
+	return containsString(h.Events, "*") || containsString(h.Events, event)
+}
+
+// webhookPayload is the JSON body delivered to webhooks. Previous is the
+// task before the change, nil for created tasks.
+type webhookPayload struct {
+	Event      string       `json:"event"`
+	Backend    string       `json:"backend"`
+	Actor      string       `json:"actor"`
+	OccurredAt time.Time    `json:"occurred_at"`
+	Task       models.Task  `json:"task"`
+	Previous   *models.Task `json:"previous,omitempty"`
+}
+
+// outboxDelivery is a payload waiting in the outbox to be delivered.
+type outboxDelivery struct {
+	ID       int
+	Event    string
+	Payload  string
+	Attempts int
+	Webhook  webhook
+}
+
+// webhookCmd represents the webhook command
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Notify URLs of task events",
+	Long: `Manage webhooks, URLs receiving a JSON POST when tasks are created, updated,
+completed or deleted. Every payload is signed with the secret of the webhook:
+the X-Tasks-Timestamp header holds the Unix time of the request, and the
+X-Tasks-Signature header "sha256=" followed by the hex HMAC-SHA256 of the
+timestamp, a dot and the body. Receivers should refuse old timestamps so that
+a captured request cannot be replayed.
+
+Events are queued in an outbox and delivered continuously by the serve
+command, other commands only queue them. Without a server, run webhook deliver
+to deliver the ones due, e.g. from cron. Failed deliveries are retried with an
+exponential backoff, up to 8 attempts.`,
+}
+
+var webhookAddCmd = &cobra.Command{
+	Use:   "add <url>",
+	Short: "Add a webhook",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		events, _ := cmd.Flags().GetStringSlice("events")
+		secret, _ := cmd.Flags().GetString("secret")
+
+		if parsed, err := url.Parse(args[0]); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			fmt.Printf("%s Invalid URL %q, use an http or https URL\n", promptui.IconBad, args[0])
+			os.Exit(1)
+		}
+		for _, event := range events {
+			if event != "*" && !containsString(webhookEvents, event) {
+				fmt.Printf("%s Invalid event %q, valid options are %s or *\n", promptui.IconBad, event, strings.Join(webhookEvents, ", "))
+				os.Exit(1)
+			}
+		}
+
+		generated := secret == ""
+		if generated {
+			random := make([]byte, 24)
+			if _, err := rand.Read(random); err != nil {
+				fmt.Printf("%s Failed to generate a secret: %v\n", promptui.IconBad, err)
+				os.Exit(1)
+			}
+			secret = hex.EncodeToString(random)
+		}
+
+		result, err := database.GetDB().Exec(`INSERT INTO webhooks (url, events, secret, created_at) VALUES (?, ?, ?, ?)`,
+			args[0], strings.Join(events, ","), secret, time.Now().UTC())
+		if err != nil {
+			fmt.Printf("%s Failed to add the webhook: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		id, _ := result.LastInsertId()
+
+		fmt.Printf("%s Webhook %d added for %s\n", promptui.IconGood, id, strings.Join(events, ", "))
+		if generated {
+			fmt.Printf("Signing secret: %s\n", secret)
+		}
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the webhooks and their pending deliveries",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		hooks, err := queryWebhooks(`ORDER BY id`)
+		if err != nil {
+			fmt.Printf("%s Failed to fetch webhooks: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if len(hooks) == 0 {
+			fmt.Println("No webhooks, add one with the webhook add command.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tURL\tEVENTS\tDELIVERED\tPENDING\tFAILED\tLAST ERROR")
+		for _, hook := range hooks {
+			var delivered, pending, failed int
+			var lastError string
+			database.GetDB().QueryRow(`SELECT
+					COUNT(delivered_at),
+					COUNT(*) - COUNT(delivered_at) - COUNT(failed_at),
+					COUNT(failed_at),
+					COALESCE((SELECT last_error FROM webhook_outbox WHERE webhook_id = ? AND last_error IS NOT NULL ORDER BY id DESC LIMIT 1), '')
+				FROM webhook_outbox WHERE webhook_id = ?`, hook.ID, hook.ID).Scan(&delivered, &pending, &failed, &lastError)
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\t%s\n", hook.ID, hook.URL, strings.Join(hook.Events, ","), delivered, pending, failed, lastError)
+		}
+		w.Flush()
+	},
+}
+
+var webhookRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a webhook and its pending deliveries",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// The deliveries of the webhook are deleted along it.
+		result, err := database.GetDB().Exec(`DELETE FROM webhooks WHERE id = ?`, args[0])
+		if err != nil {
+			fmt.Printf("%s Failed to remove the webhook: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if removed, _ := result.RowsAffected(); removed == 0 {
+			fmt.Printf("%s No webhook with ID %s\n", promptui.IconBad, args[0])
+			os.Exit(1)
+		}
+		fmt.Printf("%s Webhook %s removed\n", promptui.IconGood, args[0])
+	},
+}
+
+var webhookTestCmd = &cobra.Command{
+	Use:   "test <id>",
+	Short: "Send a test event to a webhook",
+	Long:  `Send a signed "test" event with a sample task to a webhook right away, bypassing the outbox.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hooks, err := queryWebhooks(`WHERE id = ?`, args[0])
+		if err != nil {
+			fmt.Printf("%s Failed to fetch the webhook: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if len(hooks) == 0 {
+			fmt.Printf("%s No webhook with ID %s\n", promptui.IconBad, args[0])
+			os.Exit(1)
+		}
+
+		if err := sendTestWebhook(context.Background(), hooks[0]); err != nil {
+			fmt.Printf("%s Delivery to %s failed: %v\n", promptui.IconBad, hooks[0].URL, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Test event delivered to %s\n", promptui.IconGood, hooks[0].URL)
+	},
+}
+
+var webhookDeliverCmd = &cobra.Command{
+	Use:   "deliver",
+	Short: "Deliver the webhook events that are due",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		delivered, failed, err := deliverWebhooks(context.Background(), 0, nil, keyring.Key)
+		if err != nil {
+			fmt.Printf("%s Failed to deliver webhooks: %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s %d delivered, %d failed and queued for a retry\n", promptui.IconGood, delivered, failed)
+	},
+}
+
+func init() {
+	webhookAddCmd.Flags().StringSlice("events", []string{"created", "completed"}, "Events to send: created, updated, completed, deleted or *")
+	webhookAddCmd.Flags().String("secret", "", "Signing secret (default: generated)")
+	webhookCmd.AddCommand(webhookAddCmd)
+	webhookCmd.AddCommand(webhookListCmd)
+	webhookCmd.AddCommand(webhookRemoveCmd)
+	webhookCmd.AddCommand(webhookTestCmd)
+	webhookCmd.AddCommand(webhookDeliverCmd)
+	rootCmd.AddCommand(webhookCmd)
+}
+
+func queryWebhooks(where string, args ...interface{}) ([]webhook, error) {
+	rows, err := database.GetDB().Query(`SELECT id, url, events, secret, created_at FROM webhooks `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []webhook
+	for rows.Next() {
+		var hook webhook
+		var events string
+		if err := rows.Scan(&hook.ID, &hook.URL, &events, &hook.Secret, &hook.CreatedAt); err != nil {
+			return nil, err
+		}
+		hook.Events = strings.Split(events, ",")
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// taskChangeEvents names the webhook events of a change. A task can be
+// updated and completed by the same change.
+func taskChangeEvents(before *models.Task, after *models.Task) []string {
+	switch {
+	case before == nil:
+		return []string{"created"}
+	case after == nil, before.DeletedAt == nil && after.DeletedAt != nil:
+		return []string{"deleted"}
+	}
+
+	events := []string{"updated"}
+	if before.Status != "completed" && after.Status == "completed" {
+		events = append(events, "completed")
+	}
+	return events
+}
+
+// enqueueWebhooks adds a delivery to the outbox for every webhook
+// subscribed to an event of the change. The payloads are encrypted when the
+// database is.
+func enqueueWebhooks(backend string, before *models.Task, after *models.Task) error {
+	hooks, err := queryWebhooks(``)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+	key, err := contentKey()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, event := range taskChangeEvents(before, after) {
+		payload := webhookPayload{Event: event, Backend: backend, Actor: currentActor(), OccurredAt: now, Previous: before}
+		if after != nil {
+			payload.Task = *after
+		} else {
+			payload.Task = *before
+			payload.Previous = nil
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		for _, hook := range hooks {
+			if !hook.accepts(event) {
+				continue
+			}
+			if _, err := database.GetDB().Exec(`INSERT INTO webhook_outbox (webhook_id, event, payload, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?)`,
+				hook.ID, event, key.EncryptString(string(data), outboxLocation(hook.ID)), now, now); err != nil {
+				return fmt.Errorf("error queuing webhook: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// sendTestWebhook sends a "test" event with a sample task to a webhook,
+// bypassing the outbox.
+func sendTestWebhook(ctx context.Context, hook webhook) error {
+	now := time.Now().UTC()
+	payload, err := json.Marshal(webhookPayload{
+		Event:      "test",
+		Backend:    "sqlite",
+		Actor:      currentActor(),
+		OccurredAt: now,
+		Task:       models.Task{Title: "Test task", Description: "Sent by tasks-cli webhook test", Status: "pending", CreatedAt: now, UpdatedAt: now},
+	})
+	if err != nil {
+		return fmt.Errorf("error building the payload: %v", err)
+	}
+	return sendWebhook(ctx, outboxDelivery{Event: "test", Payload: string(payload), Webhook: hook})
+}
+
+// deliverWebhooks sends the deliveries of the outbox that are due once, at
+// most limit of them when limit is not 0, rescheduling the failed ones with an
+// exponential backoff. Each delivery is claimed before it is sent, so that
+// other processes delivering the outbox skip it. mu, when not nil, is held
+// while the outbox is read and updated but not during the requests.
+// Deliveries interrupted by the end of ctx are released as they were.
+// Encrypted payloads are decrypted with the key of the database asked from
+// keys.
+func deliverWebhooks(ctx context.Context, limit int, mu *sync.Mutex, keys encryption.KeyFunc) (delivered int, failed int, err error) {
+	lock := func() {
+		if mu != nil {
+			mu.Lock()
+		}
+	}
+	unlock := func() {
+		if mu != nil {
+			mu.Unlock()
+		}
+	}
+
+	for limit == 0 || delivered+failed < limit {
+		if ctx.Err() != nil {
+			break
+		}
+		lock()
+		delivery, err := claimWebhookDelivery()
+		if err == nil && delivery != nil {
+			if err = decryptPayload(delivery, keys); err != nil {
+				releaseWebhookDelivery(*delivery)
+			}
+		}
+		unlock()
+		if err != nil {
+			return delivered, failed, err
+		}
+		if delivery == nil {
+			break
+		}
+
+		sendErr := sendWebhook(ctx, *delivery)
+		if sendErr != nil && ctx.Err() != nil {
+			lock()
+			err = releaseWebhookDelivery(*delivery)
+			unlock()
+			return delivered, failed, err
+		}
+		if sendErr == nil {
+			delivered++
+		} else {
+			failed++
+		}
+
+		lock()
+		err = recordWebhookAttempt(*delivery, sendErr)
+		unlock()
+		if err != nil {
+			return delivered, failed, err
+		}
+	}
+	return delivered, failed, nil
+}
+
+// claimWebhookDelivery claims the oldest delivery of the outbox that is due
+// and not claimed by another process for webhookClaim, nil when there is
+// none.
+func claimWebhookDelivery() (*outboxDelivery, error) {
+	now := time.Now().UTC()
+	var delivery outboxDelivery
+	err := database.GetDB().QueryRow(`UPDATE webhook_outbox SET claimed_until = ?
+		WHERE id = (
+			SELECT id FROM webhook_outbox
+			WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ? AND (claimed_until IS NULL OR claimed_until <= ?)
+			ORDER BY id LIMIT 1
+		)
+		RETURNING id, event, payload, attempts, webhook_id,
+			(SELECT url FROM webhooks WHERE id = webhook_id), (SELECT secret FROM webhooks WHERE id = webhook_id)`,
+		now.Add(webhookClaim), now, now).Scan(&delivery.ID, &delivery.Event, &delivery.Payload, &delivery.Attempts,
+		&delivery.Webhook.ID, &delivery.Webhook.URL, &delivery.Webhook.Secret)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// releaseWebhookDelivery gives up the claim on a delivery that was not sent.
+func releaseWebhookDelivery(delivery outboxDelivery) error {
+	_, err := database.GetDB().Exec(`UPDATE webhook_outbox SET claimed_until = NULL WHERE id = ?`, delivery.ID)
+	return err
+}
+
+// outboxLocation returns the location of the payloads queued for the webhook
+// id.
+func outboxLocation(id int) encryption.Location {
+	return encryption.Location{Table: "webhook_outbox", Column: "payload", Row: strconv.Itoa(id)}
+}
+
+// decryptPayload decrypts the payload of a delivery when it is encrypted,
+// asking keys for the key of the database.
+func decryptPayload(delivery *outboxDelivery, keys encryption.KeyFunc) error {
+	if !encryption.IsEncryptedString(delivery.Payload) {
+		return nil
+	}
+	key, err := tasks.DatabaseKey(database.GetDB(), keys)
+	if err != nil {
+		return err
+	}
+	payload, err := key.DecryptString(delivery.Payload, outboxLocation(delivery.Webhook.ID))
+	if err != nil {
+		return fmt.Errorf("error decrypting webhook delivery %d: %w", delivery.ID, err)
+	}
+	delivery.Payload = payload
+	return nil
+}
+
+// recordWebhookAttempt marks a delivery delivered, or schedules its next
+// attempt after sendErr, giving up after webhookMaxAttempts.
+func recordWebhookAttempt(delivery outboxDelivery, sendErr error) error {
+	db := database.GetDB()
+	now := time.Now().UTC()
+	if sendErr == nil {
+		_, err := db.Exec(`UPDATE webhook_outbox SET attempts = attempts + 1, delivered_at = ?, last_error = NULL, claimed_until = NULL WHERE id = ?`, now, delivery.ID)
+		return err
+	}
+
+	attempts := delivery.Attempts + 1
+	var failedAt interface{}
+	if attempts >= webhookMaxAttempts {
+		failedAt = now
+	}
+	_, err := db.Exec(`UPDATE webhook_outbox SET attempts = ?, next_attempt_at = ?, last_error = ?, failed_at = ?, claimed_until = NULL WHERE id = ?`,
+		attempts, now.Add(webhookBackoff(attempts)), sendErr.Error(), failedAt, delivery.ID)
+	return err
+}
+
+// webhookBackoff returns the delay before the next attempt after the given
+// number of failed attempts: 30s, 1m, 2m, … up to an hour.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookBaseBackoff
+	for i := 1; i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookMaxBackoff {
+		backoff = webhookMaxBackoff
+	}
+	return backoff
+}
+
+// sendWebhook POSTs the signed payload, any status but 2xx is an error.
+func sendWebhook(ctx context.Context, delivery outboxDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "tasks-cli-webhook")
+	request.Header.Set("X-Tasks-Event", delivery.Event)
+	request.Header.Set("X-Tasks-Delivery", fmt.Sprint(delivery.ID))
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("X-Tasks-Timestamp", timestamp)
+	request.Header.Set("X-Tasks-Signature", signWebhook(delivery.Webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 200))
+		return fmt.Errorf("%s: %s", response.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// signWebhook returns the X-Tasks-Signature header value of a payload sent
+// at timestamp.
+func signWebhook(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
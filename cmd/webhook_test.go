@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+)
+
+// webhookReceiver is an httptest server recording the webhooks it receives,
+// answering them with status.
+type webhookReceiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, receivedWebhook{header: r.Header.Clone(), body: body})
+		w.WriteHeader(receiver.status)
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+// addTestWebhook adds a webhook of every event as webhook add does.
+func addTestWebhook(t *testing.T, url string, secret string) {
+	t.Helper()
+	if _, err := database.GetDB().Exec(`INSERT INTO webhooks (url, events, secret, created_at) VALUES (?, '*', ?, ?)`, url, secret, time.Now().UTC()); err != nil {
+		t.Fatalf("adding the webhook: %v", err)
+	}
+}
+
+// outboxRow is the delivery state of an outbox entry.
+type outboxRow struct {
+	attempts      int
+	nextAttemptAt time.Time
+	delivered     bool
+	failed        bool
+	lastError     string
+}
+
+func readOutbox(t *testing.T) []outboxRow {
+	t.Helper()
+	rows, err := database.GetDB().Query(`SELECT attempts, next_attempt_at, delivered_at IS NOT NULL, failed_at IS NOT NULL, COALESCE(last_error, '') FROM webhook_outbox ORDER BY id`)
+	if err != nil {
+		t.Fatalf("reading the outbox: %v", err)
+	}
+	defer rows.Close()
+	var outbox []outboxRow
+	for rows.Next() {
+		var row outboxRow
+		if err := rows.Scan(&row.attempts, &row.nextAttemptAt, &row.delivered, &row.failed, &row.lastError); err != nil {
+			t.Fatalf("reading the outbox: %v", err)
+		}
+		outbox = append(outbox, row)
+	}
+	return outbox
+}
+
+// verifySignature checks the X-Tasks-Timestamp and X-Tasks-Signature headers
+// of a webhook the way a receiver would.
+func verifySignature(t *testing.T, secret string, request receivedWebhook) {
+	t.Helper()
+	timestamp := request.header.Get("X-Tasks-Timestamp")
+	if sent, err := strconv.ParseInt(timestamp, 10, 64); err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("X-Tasks-Timestamp = %q, want the time of the request", timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(request.body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := request.header.Get("X-Tasks-Signature"); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("X-Tasks-Signature = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	useTestWorkspace(t)
+	receiver := newWebhookReceiver(t)
+	addTestWebhook(t, receiver.URL, "s3cret")
+
+	store := newSQLiteStore()
+	task, err := store.Add(models.Task{Title: "Call Acme", Status: "pending"})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	delivered, failed, err := deliverWebhooks(context.Background(), 0, nil, keyring.Key)
+	if err != nil || delivered != 1 || failed != 0 {
+		t.Fatalf("deliverWebhooks = %d delivered, %d failed, %v; want 1 delivered", delivered, failed, err)
+	}
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("received %d webhooks, want 1", len(requests))
+	}
+	verifySignature(t, "s3cret", requests[0])
+	if event := requests[0].header.Get("X-Tasks-Event"); event != "created" {
+		t.Errorf("X-Tasks-Event = %q, want created", event)
+	}
+	var payload webhookPayload
+	if err := json.Unmarshal(requests[0].body, &payload); err != nil || payload.Task.Title != "Call Acme" || payload.Actor != "tester" {
+		t.Errorf("payload = %+v, %v", payload, err)
+	}
+	if outbox := readOutbox(t); len(outbox) != 1 || !outbox[0].delivered || outbox[0].attempts != 1 {
+		t.Errorf("outbox = %+v, want one delivered entry", outbox)
+	}
+
+	// A failed delivery is retried after a backoff, and given up after
+	// webhookMaxAttempts.
+	receiver.setStatus(http.StatusInternalServerError)
+	task.Status = "completed"
+	if err := store.Update(task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	before := time.Now().UTC()
+	delivered, failed, err = deliverWebhooks(context.Background(), 0, nil, keyring.Key)
+	if err != nil || delivered != 0 || failed != 2 {
+		t.Fatalf("deliverWebhooks = %d delivered, %d failed, %v; want the updated and completed events failed", delivered, failed, err)
+	}
+	outbox := readOutbox(t)
+	for _, row := range outbox[1:] {
+		if row.delivered || row.failed || row.attempts != 1 || !strings.Contains(row.lastError, "500") {
+			t.Errorf("failed entry = %+v, want 1 attempt with the error", row)
+		}
+		if wait := row.nextAttemptAt.Sub(before); wait < webhookBaseBackoff || wait > webhookBaseBackoff+time.Minute {
+			t.Errorf("next attempt in %v, want %v", wait, webhookBaseBackoff)
+		}
+	}
+
+	if delivered, failed, _ := deliverWebhooks(context.Background(), 0, nil, keyring.Key); delivered+failed != 0 {
+		t.Errorf("deliverWebhooks retried %d deliveries before their backoff", delivered+failed)
+	}
+
+	if _, err := database.GetDB().Exec(`UPDATE webhook_outbox SET attempts = ?, next_attempt_at = ? WHERE delivered_at IS NULL`,
+		webhookMaxAttempts-1, before.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, failed, err := deliverWebhooks(context.Background(), 1, nil, keyring.Key); err != nil || failed != 1 {
+		t.Fatalf("deliverWebhooks with a limit of 1 = %d failed, %v", failed, err)
+	}
+	outbox = readOutbox(t)
+	if !outbox[1].failed || outbox[1].attempts != webhookMaxAttempts || outbox[2].attempts != webhookMaxAttempts-1 {
+		t.Errorf("outbox = %+v, want the first retry given up and the second left for the next run", outbox)
+	}
+}
+
+func TestWebhookDeliveryReleasesLock(t *testing.T) {
+	useTestWorkspace(t)
+	var mu sync.Mutex
+	locked := true
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The API of serve takes the lock while a receiver answers.
+		if mu.TryLock() {
+			locked = false
+			mu.Unlock()
+		}
+	}))
+	t.Cleanup(receiver.Close)
+	addTestWebhook(t, receiver.URL, "s3cret")
+	if _, err := newSQLiteStore().Add(models.Task{Title: "Call Acme", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if delivered, _, err := deliverWebhooks(context.Background(), 0, &mu, keyring.Key); err != nil || delivered != 1 {
+		t.Fatalf("deliverWebhooks = %d delivered, %v", delivered, err)
+	}
+	if locked {
+		t.Errorf("the lock was held during the request")
+	}
+}
+
+func TestWebhookDeliveryClaims(t *testing.T) {
+	useTestWorkspace(t)
+	receiver := newWebhookReceiver(t)
+	addTestWebhook(t, receiver.URL, "s3cret")
+	if _, err := newSQLiteStore().Add(models.Task{Title: "Call Acme", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// Another process claimed the delivery and is sending it.
+	claimed, err := claimWebhookDelivery()
+	if err != nil || claimed == nil {
+		t.Fatalf("claimWebhookDelivery = %+v, %v", claimed, err)
+	}
+	if next, err := claimWebhookDelivery(); err != nil || next != nil {
+		t.Errorf("claimWebhookDelivery of a claimed delivery = %+v, %v, want none", next, err)
+	}
+	if delivered, failed, err := deliverWebhooks(context.Background(), 0, nil, keyring.Key); err != nil || delivered+failed != 0 {
+		t.Errorf("deliverWebhooks = %d delivered, %d failed, %v; want the claimed delivery skipped", delivered, failed, err)
+	}
+
+	// The claim of a process that died expires.
+	if _, err := database.GetDB().Exec(`UPDATE webhook_outbox SET claimed_until = ?`, time.Now().UTC().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if delivered, _, err := deliverWebhooks(context.Background(), 0, nil, keyring.Key); err != nil || delivered != 1 {
+		t.Errorf("deliverWebhooks once the claim expired = %d delivered, %v", delivered, err)
+	}
+	if requests := receiver.received(); len(requests) != 1 {
+		t.Errorf("received %d webhooks, want 1", len(requests))
+	}
+}
+
+func TestWebhookRemoveClearsOutbox(t *testing.T) {
+	useTestWorkspace(t)
+	addTestWebhook(t, "http://localhost:1", "s3cret")
+	if _, err := newSQLiteStore().Add(models.Task{Title: "Call Acme", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if outbox := readOutbox(t); len(outbox) != 1 {
+		t.Fatalf("outbox = %+v, want one delivery", outbox)
+	}
+
+	webhookRemoveCmd.Run(webhookRemoveCmd, []string{"1"})
+	if outbox := readOutbox(t); len(outbox) != 0 {
+		t.Errorf("outbox once the webhook was removed = %+v, want it empty", outbox)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, test := range tests {
+		if got := webhookBackoff(test.attempts); got != test.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", test.attempts, got, test.want)
+		}
+	}
+}
+
+func TestSendTestWebhook(t *testing.T) {
+	useTestWorkspace(t)
+	receiver := newWebhookReceiver(t)
+
+	hook := webhook{ID: 1, URL: receiver.URL, Secret: "s3cret"}
+	if err := sendTestWebhook(context.Background(), hook); err != nil {
+		t.Fatalf("sendTestWebhook: %v", err)
+	}
+	requests := receiver.received()
+	if len(requests) != 1 || requests[0].header.Get("X-Tasks-Event") != "test" {
+		t.Fatalf("received %+v, want one test event", requests)
+	}
+	verifySignature(t, "s3cret", requests[0])
+
+	receiver.setStatus(http.StatusGone)
+	if err := sendTestWebhook(context.Background(), hook); err == nil || !strings.Contains(err.Error(), "410") {
+		t.Errorf("sendTestWebhook to a failing receiver: %v, want the 410 status", err)
+	}
+	if outbox := readOutbox(t); len(outbox) != 0 {
+		t.Errorf("the test event went through the outbox: %+v", outbox)
+	}
+}
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// webFiles is the single page UI served by the serve command.
+//
+//go:embed web
+var webFiles embed.FS
+
+// webUIHandler serves the embedded UI, which talks to the API with the
+// token the user enters in the browser.
+func webUIHandler() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(files)
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/tabwriter"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"github.com/unf6/testing/pkg/config"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+var workspaceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// workspaceCmd represents the workspace command
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Manage workspaces, sets of tasks with their own database and CSV file",
+	Long: `Manage workspaces, sets of tasks with their own database and CSV file such as
+work and personal tasks. Commands use the workspace selected with --workspace,
+TASKS_CLI_WORKSPACE or "workspace use", the default workspace otherwise. The
+history, timers, tokens and webhooks are kept per workspace. Without
+--workspace, commands run in a repository with a task store created by init
+use that store instead.`,
+	PersistentPreRun:  loadSettingsOnly,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var workspaceCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a workspace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		file := loadConfigFile()
+		if !workspaceNamePattern.MatchString(name) {
+			fmt.Printf("%s Invalid workspace name %q, use letters, digits, - and _\n", promptui.IconBad, name)
+			os.Exit(1)
+		}
+		if _, ok := file.Workspaces[name]; ok || name == config.DefaultWorkspace {
+			fmt.Printf("%s Workspace %q already exists\n", promptui.IconBad, name)
+			os.Exit(1)
+		}
+
+		var created config.Workspace
+		for flag, path := range map[string]*string{"db": &created.DB, "csv": &created.CSV, "backups": &created.Backups} {
+			value, _ := cmd.Flags().GetString(flag)
+			if value == "" {
+				continue
+			}
+			absolute, err := filepath.Abs(value)
+			if err != nil {
+				fmt.Printf("%s Invalid --%s path: %v\n", promptui.IconBad, flag, err)
+				os.Exit(1)
+			}
+			*path = absolute
+		}
+
+		if file.Workspaces == nil {
+			file.Workspaces = make(map[string]config.Workspace)
+		}
+		file.Workspaces[name] = created
+		if err := config.Save(cfgFile, file); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		paths, _ := file.Workspace(name)
+		fmt.Printf("%s Created workspace %s\n", promptui.IconGood, name)
+		fmt.Printf("  Database: %s\n  CSV file: %s\n", paths.DB, paths.CSV)
+		fmt.Printf("Switch to it with: tasks-cli workspace use %s\n", name)
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the workspaces",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := loadConfigFile()
+		current := currentWorkspaceName()
+
+		w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tDATABASE\tCSV FILE")
+		// The store of the repository is used instead of the workspace.
+		if local, ok := repositoryWorkspace(); ok {
+			fmt.Fprintf(w, "*\t%s\t%s\t%s\n", local.Name, local.DB, local.CSV)
+			current = ""
+		}
+		for _, name := range file.WorkspaceNames() {
+			paths, _ := file.Workspace(name)
+			marker := ""
+			if name == current {
+				marker = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", marker, name, paths.DB, paths.CSV)
+		}
+		w.Flush()
+	},
+}
+
+var workspaceUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Select the workspace commands use",
+	Long: `Select the workspace commands use when no --workspace is given. With
+--profile, the workspace is only selected for that profile.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		file := loadConfigFile()
+		if _, err := file.Workspace(name); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		value := name
+		if name == config.DefaultWorkspace {
+			value = ""
+		}
+		if err := setSetting(&file, "workspace", value); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		if err := config.Save(cfgFile, file); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s Using workspace %s\n", promptui.IconGood, name)
+	},
+}
+
+var workspaceRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a workspace, keeping its database and CSV file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		file := loadConfigFile()
+		if name == config.DefaultWorkspace {
+			fmt.Printf("%s The default workspace cannot be removed\n", promptui.IconBad)
+			os.Exit(1)
+		}
+		paths, err := file.Workspace(name)
+		if err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		delete(file.Workspaces, name)
+		// Settings selecting the workspace fall back to the default one.
+		if file.Settings.Workspace == name {
+			file.Settings.Workspace = ""
+		}
+		for profileName, profile := range file.Profiles {
+			if profile.Workspace == name {
+				profile.Workspace = ""
+				file.Profiles[profileName] = profile
+			}
+		}
+		if err := config.Save(cfgFile, file); err != nil {
+			fmt.Printf("%s %v\n", promptui.IconBad, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("%s Removed workspace %s, its files were kept:\n", promptui.IconGood, name)
+		fmt.Printf("  Database: %s\n  CSV file: %s\n", paths.DB, paths.CSV)
+	},
+}
+
+func init() {
+	workspaceCreateCmd.Flags().String("db", "", "Database of the workspace (default is tasks.db in its directory of the data directory)")
+	workspaceCreateCmd.Flags().String("csv", "", "CSV file of the workspace (default is tasks.csv in its directory of the data directory)")
+	workspaceCreateCmd.Flags().String("backups", "", "Backup directory of the workspace (default is backups in its directory of the data directory)")
+
+	workspaceCmd.AddCommand(workspaceCreateCmd)
+	workspaceCmd.AddCommand(workspaceListCmd)
+	workspaceCmd.AddCommand(workspaceUseCmd)
+	workspaceCmd.AddCommand(workspaceRemoveCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+// currentWorkspaceName returns the workspace selected with --workspace or
+// the settings.
+func currentWorkspaceName() string {
+	switch {
+	case cfgWorkspace != "":
+		return cfgWorkspace
+	case cfg.Workspace != "":
+		return cfg.Workspace
+	}
+	return config.DefaultWorkspace
+}
+
+// listAllWorkspaces lists the tasks of the backend choice in every workspace,
+// with a workspace column.
+func listAllWorkspaces(choice string, format string, archived bool) {
+	if choice == "Remote" {
+		fmt.Printf("%s --all-workspaces is not supported with the remote backend\n", promptui.IconBad)
+		os.Exit(1)
+	}
+
+	file := loadConfigFile()
+	var data []DBTask
+	for _, name := range file.WorkspaceNames() {
+		paths, _ := file.Workspace(name)
+		rows, err := workspaceRows(paths, choice == "CSV File", archived)
+		if err != nil {
+			fmt.Printf("%s Workspace %s: %v\n", promptui.IconBad, name, err)
+			os.Exit(1)
+		}
+		for i := range rows {
+			rows[i].Workspace = name
+		}
+		data = append(data, rows...)
+	}
+	printTasks(data, format)
+}
+
+// workspaceRows returns the display data of the tasks of a workspace. A
+// workspace whose files do not exist yet has no tasks, they are not created.
+func workspaceRows(paths config.Workspace, fromCSV bool, archived bool) ([]DBTask, error) {
+	var db *sql.DB
+	if paths.DB == workspace.DB {
+		db = database.GetDB()
+	} else if _, err := os.Stat(paths.DB); err == nil {
+		// Other workspaces are only read, they are migrated once in use.
+		db, err = database.OpenReadOnly(paths.DB)
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+	}
+
+	var store taskStore
+	if fromCSV {
+		if _, err := os.Stat(paths.CSV); os.IsNotExist(err) {
+			return nil, nil
+		}
+		store = &tasks.CSVStore{Path: paths.CSV, Keys: keyring.Key}
+	} else {
+		if db == nil {
+			return nil, nil
+		}
+		store = &tasks.SQLiteStore{DB: db, Keys: keyring.Key}
+	}
+	return taskRows(store, db, archived)
+}
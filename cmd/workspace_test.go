@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/config"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+func TestWorkspaceRowsReadOnly(t *testing.T) {
+	useTestWorkspace(t)
+
+	// Another workspace last used by a release without webhooks.
+	dir := t.TempDir()
+	other := config.Workspace{Name: "work", DB: filepath.Join(dir, "tasks.db"), CSV: filepath.Join(dir, "tasks.csv")}
+	db, err := database.Open(other.DB)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := (&tasks.SQLiteStore{DB: db}).Add(models.Task{Title: "Call Acme", Status: "pending"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := db.Exec(`DROP TABLE webhook_outbox; DROP TABLE webhooks`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	before, err := os.ReadFile(other.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := workspaceRows(other, false, false)
+	if err != nil {
+		t.Fatalf("workspaceRows: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Title != "Call Acme" {
+		t.Errorf("workspaceRows = %+v, want Call Acme", rows)
+	}
+	if after, err := os.ReadFile(other.DB); err != nil || !bytes.Equal(before, after) {
+		t.Errorf("the database of the other workspace was written to: %v", err)
+	}
+
+	// A workspace without a database has no tasks, none is created.
+	empty := config.Workspace{Name: "empty", DB: filepath.Join(dir, "empty.db"), CSV: filepath.Join(dir, "empty.csv")}
+	if rows, err := workspaceRows(empty, false, false); err != nil || len(rows) != 0 {
+		t.Errorf("workspaceRows of an empty workspace = %+v, %v", rows, err)
+	}
+	if _, err := os.Stat(empty.DB); !os.IsNotExist(err) {
+		t.Errorf("workspaceRows created %s: %v", empty.DB, err)
+	}
+}
@@ -5,9 +5,14 @@ import "time"
 // Task represents the structure of a task.
 type Task struct {
     ID          int       `json:"id"`
+    UID         string    `json:"uid"`
     Title       string    `json:"title"`
     Description string    `json:"description"`
     Status      string    `json:"status"`
+    Project     string    `json:"project,omitempty"`
+    Tags        []string  `json:"tags,omitempty"`
     CreatedAt   time.Time `json:"created_at"`
     UpdatedAt   time.Time `json:"updated_at"`
+    DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+    ArchivedAt  *time.Time `json:"archived_at,omitempty"`
 }
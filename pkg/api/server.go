@@ -0,0 +1,401 @@
+// Package api serves the tasks of a task service as the JSON HTTP API of the
+// serve command, the API tasks.Client talks to.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// DefaultPageSize is the number of tasks listed without a limit parameter.
+const DefaultPageSize = 50
+
+// Server serves the tasks of Service. Handlers left nil are not served.
+type Server struct {
+	Service *tasks.Service
+	// Authenticate returns the active token with a secret, or nil when
+	// there is none. Requests go through without a token when it is nil.
+	Authenticate func(secret string) (*Token, error)
+	// Events serves GET /api/events, UI the other GET requests outside of
+	// /api/, which do not need a token.
+	Events http.Handler
+	UI     http.Handler
+	// Operation is called before a request changes tasks with its token,
+	// nil without authentication. The function it returns is called once
+	// the changes are made.
+	Operation func(token *Token) func()
+	// Mu serializes database access with the other users of the database,
+	// the server uses its own when it is nil.
+	Mu *sync.Mutex
+}
+
+// Token is the API token a request is authenticated with.
+type Token struct {
+	Name string
+	// Write lets the token change tasks, other tokens can only read them.
+	Write bool
+}
+
+// Error is the body of every error response. Fields holds the validation
+// error of each invalid field.
+type Error struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// createTaskRequest is the body of POST /api/tasks. The server sets the ID,
+// UID and timestamps of the new task.
+type createTaskRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+}
+
+type tokenContextKey struct{}
+
+// statusRecorder remembers the status code of a response for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController flush the event stream.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Handler returns the handler of the API, authenticating and logging every
+// request.
+func (s *Server) Handler() http.Handler {
+	if s.Mu == nil {
+		s.Mu = &sync.Mutex{}
+	}
+	return s.middleware(s.routes())
+}
+
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/tasks", s.listTasks)
+	mux.HandleFunc("POST /api/tasks", s.createTask)
+	mux.HandleFunc("GET /api/tasks/{id}", s.getTask)
+	mux.HandleFunc("PATCH /api/tasks/{id}", s.patchTask)
+	mux.HandleFunc("DELETE /api/tasks/{id}", s.deleteTask)
+	if s.Events != nil {
+		mux.Handle("GET /api/events", s.Events)
+	}
+	if s.UI != nil {
+		mux.Handle("GET /", s.UI)
+	}
+	return mux
+}
+
+// middleware authenticates and logs every request.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		token, ok := s.authorize(recorder, r)
+		if ok {
+			if token != nil {
+				r = r.WithContext(context.WithValue(r.Context(), tokenContextKey{}, token))
+			}
+			next.ServeHTTP(recorder, r)
+		}
+
+		name := "-"
+		if token != nil {
+			name = token.Name
+		}
+		log.Printf("%s %s %d %s token=%s", r.Method, r.URL.RequestURI(), recorder.status, time.Since(start).Round(time.Millisecond), name)
+	})
+}
+
+// authorize checks the bearer token of the request against its method and
+// writes the error response when it is not allowed.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) (*Token, bool) {
+	// The web UI is public, it asks for a token to call the API.
+	if s.Authenticate == nil || !strings.HasPrefix(r.URL.Path, "/api/") {
+		return nil, true
+	}
+
+	secret, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || secret == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tasks-cli"`)
+		WriteError(w, http.StatusUnauthorized, Error{Error: "missing API token"})
+		return nil, false
+	}
+
+	s.Mu.Lock()
+	token, err := s.Authenticate(strings.TrimSpace(secret))
+	s.Mu.Unlock()
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, Error{Error: err.Error()})
+		return nil, false
+	}
+	if token == nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tasks-cli", error="invalid_token"`)
+		WriteError(w, http.StatusUnauthorized, Error{Error: "invalid or revoked API token"})
+		return nil, false
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !token.Write {
+		WriteError(w, http.StatusForbidden, Error{Error: fmt.Sprintf("token %q is read-only", token.Name)})
+		return token, false
+	}
+	return token, true
+}
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	fields := make(map[string]string)
+
+	limit, offset := DefaultPageSize, 0
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > tasks.MaxPageSize {
+			fields["limit"] = fmt.Sprintf("must be a number between 1 and %d", tasks.MaxPageSize)
+		}
+		limit = n
+	}
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			fields["offset"] = "must be a positive number"
+		}
+		offset = n
+	}
+
+	view := query.Get("view")
+	if view == "" {
+		view = "active"
+	}
+	if view != "active" && view != "archived" && view != "trashed" && view != "all" {
+		fields["view"] = "must be one of active, archived, trashed, all"
+	}
+
+	var statuses []string
+	if value := query.Get("status"); value != "" {
+		statuses = strings.Split(value, ",")
+	}
+	if len(fields) > 0 {
+		WriteError(w, http.StatusUnprocessableEntity, Error{Error: "invalid query parameters", Fields: fields})
+		return
+	}
+
+	s.Mu.Lock()
+	page, err := s.Service.List(r.Context(), tasks.ListOptions{
+		Status: statuses,
+		Query:  query.Get("q"),
+		View:   tasks.View(view),
+		Limit:  limit,
+		Offset: offset,
+	})
+	s.Mu.Unlock()
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, page)
+}
+
+// lookupTask finds the task of the {id} path value, archived and trashed
+// tasks included, and writes the error response when there is none.
+func (s *Server) lookupTask(w http.ResponseWriter, r *http.Request) (models.Task, bool) {
+	task, err := s.Service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return task, false
+	}
+	return task, true
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+
+	if task, ok := s.lookupTask(w, r); ok {
+		WriteJSON(w, http.StatusOK, task)
+	}
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var request createTaskRequest
+	if !decodeJSON(w, r, &request) {
+		return
+	}
+
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	defer s.beginOperation(r)()
+
+	created, err := s.Service.Create(r.Context(), models.Task{
+		Title:       request.Title,
+		Description: request.Description,
+		Status:      request.Status,
+		Project:     request.Project,
+		Tags:        request.Tags,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/tasks/%d", created.ID))
+	WriteJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) patchTask(w http.ResponseWriter, r *http.Request) {
+	var patch map[string]json.RawMessage
+	if !decodeJSON(w, r, &patch) {
+		return
+	}
+
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	defer s.beginOperation(r)()
+
+	task, ok := s.lookupTask(w, r)
+	if !ok {
+		return
+	}
+
+	fields := make(map[string]string)
+	for name, value := range patch {
+		var target interface{}
+		switch name {
+		case "title":
+			target = &task.Title
+		case "description":
+			target = &task.Description
+		case "status":
+			target = &task.Status
+		case "project":
+			target = &task.Project
+		case "tags":
+			target = &task.Tags
+		case "deleted_at":
+			target = &task.DeletedAt
+		case "archived_at":
+			target = &task.ArchivedAt
+		default:
+			fields[name] = "cannot be changed"
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			fields[name] = "has an invalid type"
+		}
+	}
+	var invalid *tasks.ValidationError
+	if errors.As(tasks.Validate(task), &invalid) {
+		maps.Copy(fields, invalid.Fields)
+	}
+	if len(fields) > 0 {
+		WriteError(w, http.StatusUnprocessableEntity, Error{Error: "invalid task", Fields: fields})
+		return
+	}
+
+	updated, err := s.Service.Update(r.Context(), task)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
+	purge, _ := strconv.ParseBool(r.URL.Query().Get("purge"))
+
+	s.Mu.Lock()
+	defer s.Mu.Unlock()
+	defer s.beginOperation(r)()
+
+	task, ok := s.lookupTask(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	if purge {
+		err = s.Service.Purge(r.Context(), strconv.Itoa(task.ID))
+	} else {
+		err = s.Service.Delete(r.Context(), strconv.Itoa(task.ID))
+	}
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// beginOperation calls Operation with the token of the request and returns
+// the function to call once its changes are made.
+func (s *Server) beginOperation(r *http.Request) func() {
+	if s.Operation == nil {
+		return func() {}
+	}
+	token, _ := r.Context().Value(tokenContextKey{}).(*Token)
+	return s.Operation(token)
+}
+
+// decodeJSON reads the JSON request body into v and writes the error
+// response when it is not valid.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err := decoder.Decode(v); err != nil {
+		message := "invalid JSON body: " + err.Error()
+		if err == io.EOF {
+			message = "request body is empty"
+		}
+		WriteError(w, http.StatusBadRequest, Error{Error: message})
+		return false
+	}
+	return true
+}
+
+// WriteJSON writes v as the JSON body of a response.
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes an error response.
+func WriteError(w http.ResponseWriter, status int, body Error) {
+	WriteJSON(w, status, body)
+}
+
+// writeServiceError writes the response of an error of the task service:
+// 422 with the invalid fields, 404 for unknown tasks, 400 for ambiguous ones.
+func writeServiceError(w http.ResponseWriter, err error) {
+	var invalid *tasks.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		WriteError(w, http.StatusUnprocessableEntity, Error{Error: "invalid task", Fields: invalid.Fields})
+	case errors.Is(err, tasks.ErrNotFound):
+		WriteError(w, http.StatusNotFound, Error{Error: err.Error()})
+	case errors.Is(err, tasks.ErrInvalid):
+		WriteError(w, http.StatusBadRequest, Error{Error: err.Error()})
+	default:
+		WriteError(w, http.StatusInternalServerError, Error{Error: err.Error()})
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+// newTestAPI returns the handler of the API without authentication on a new
+// database.
+func newTestAPI(t *testing.T) http.Handler {
+	t.Helper()
+	previous := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	store, err := tasks.OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { store.DB.Close() })
+
+	server := &Server{Service: tasks.NewService(store)}
+	return server.Handler()
+}
+
+// serveTestRequest sends a request to the API and decodes the JSON response
+// into out when it is not nil.
+func serveTestRequest(t *testing.T, handler http.Handler, method string, target string, body string, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, target, reader))
+	if out != nil {
+		if err := json.Unmarshal(recorder.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decoding %q: %v", method, target, recorder.Body, err)
+		}
+	}
+	return recorder
+}
+
+func TestAPICreateTask(t *testing.T) {
+	handler := newTestAPI(t)
+
+	var created models.Task
+	response := serveTestRequest(t, handler, http.MethodPost, "/api/tasks",
+		`{"id": 42, "uid": "01ARZ3NDEKTSV4RRFFQ69G5FAV", "title": " Call Acme ", "description": "About the quote",
+		  "deleted_at": "2024-01-01T00:00:00Z", "archived_at": "2024-01-01T00:00:00Z", "created_at": "2001-01-01T00:00:00Z"}`,
+		&created)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("POST /api/tasks = %d %s, want 201", response.Code, response.Body)
+	}
+	if location := response.Header().Get("Location"); location != fmt.Sprintf("/api/tasks/%d", created.ID) {
+		t.Errorf("Location = %q", location)
+	}
+	// Only the title, description and status are taken from the client.
+	if created.ID == 42 || created.UID == "01ARZ3NDEKTSV4RRFFQ69G5FAV" || created.DeletedAt != nil || created.ArchivedAt != nil || created.CreatedAt.Year() == 2001 {
+		t.Errorf("created task %+v kept fields set by the client", created)
+	}
+	if created.Title != "Call Acme" || created.Description != "About the quote" || created.Status != "pending" {
+		t.Errorf("created task = %+v", created)
+	}
+
+	var fetched models.Task
+	if response := serveTestRequest(t, handler, http.MethodGet, "/api/tasks/"+created.UID[:8], "", &fetched); response.Code != http.StatusOK || fetched.ID != created.ID {
+		t.Errorf("GET by UID prefix = %d %+v", response.Code, fetched)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	handler := newTestAPI(t)
+	serveTestRequest(t, handler, http.MethodPost, "/api/tasks", `{"title": "Call Acme"}`, nil)
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		status int
+		fields []string
+	}{
+		{http.MethodGet, "/api/tasks/99", "", http.StatusNotFound, nil},
+		{http.MethodPatch, "/api/tasks/99", `{"status": "completed"}`, http.StatusNotFound, nil},
+		{http.MethodDelete, "/api/tasks/99", "", http.StatusNotFound, nil},
+		{http.MethodPost, "/api/tasks", `{"title": " ", "status": "done"}`, http.StatusUnprocessableEntity, []string{"title", "status"}},
+		{http.MethodPatch, "/api/tasks/1", `{"status": "done", "uid": "x"}`, http.StatusUnprocessableEntity, []string{"status", "uid"}},
+		{http.MethodPatch, "/api/tasks/1", `{"title": 3}`, http.StatusUnprocessableEntity, []string{"title"}},
+		{http.MethodGet, "/api/tasks?limit=0&offset=-1&view=old", "", http.StatusUnprocessableEntity, []string{"limit", "offset", "view"}},
+		{http.MethodPost, "/api/tasks", `{"title": `, http.StatusBadRequest, nil},
+		{http.MethodPost, "/api/tasks", "", http.StatusBadRequest, nil},
+		{http.MethodPatch, "/api/tasks/1", `[]`, http.StatusBadRequest, nil},
+	}
+	for _, test := range tests {
+		var body Error
+		response := serveTestRequest(t, handler, test.method, test.target, test.body, &body)
+		if response.Code != test.status || body.Error == "" {
+			t.Errorf("%s %s %s = %d %+v, want %d", test.method, test.target, test.body, response.Code, body, test.status)
+			continue
+		}
+		if len(body.Fields) != len(test.fields) {
+			t.Errorf("%s %s %s: fields = %v, want %v", test.method, test.target, test.body, body.Fields, test.fields)
+		}
+		for _, field := range test.fields {
+			if body.Fields[field] == "" {
+				t.Errorf("%s %s %s: no error for the %s field in %v", test.method, test.target, test.body, field, body.Fields)
+			}
+		}
+	}
+}
+
+func TestAPIListPagination(t *testing.T) {
+	handler := newTestAPI(t)
+	for i := 1; i <= 7; i++ {
+		status := "pending"
+		if i%2 == 0 {
+			status = "completed"
+		}
+		serveTestRequest(t, handler, http.MethodPost, "/api/tasks", fmt.Sprintf(`{"title": "Task %d", "status": %q}`, i, status), nil)
+	}
+	serveTestRequest(t, handler, http.MethodDelete, "/api/tasks/7", "", nil)
+
+	tests := []struct {
+		query string
+		ids   []int
+		total int
+		limit int
+	}{
+		{"", []int{1, 2, 3, 4, 5, 6}, 6, DefaultPageSize},
+		{"?limit=4", []int{1, 2, 3, 4}, 6, 4},
+		{"?limit=4&offset=4", []int{5, 6}, 6, 4},
+		{"?offset=10", []int{}, 6, DefaultPageSize},
+		{"?status=completed&limit=2&offset=1", []int{4, 6}, 3, 2},
+		{"?q=task+3", []int{3}, 1, DefaultPageSize},
+		{"?view=trashed", []int{7}, 1, DefaultPageSize},
+		{"?view=all&offset=5", []int{6, 7}, 7, DefaultPageSize},
+	}
+	for _, test := range tests {
+		var page tasks.Page
+		if response := serveTestRequest(t, handler, http.MethodGet, "/api/tasks"+test.query, "", &page); response.Code != http.StatusOK {
+			t.Errorf("GET /api/tasks%s = %d %s", test.query, response.Code, response.Body)
+			continue
+		}
+		ids := []int{}
+		for _, task := range page.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(test.ids) || page.Total != test.total || page.Limit != test.limit {
+			t.Errorf("GET /api/tasks%s = tasks %v, total %d, limit %d; want %v, %d, %d",
+				test.query, ids, page.Total, page.Limit, test.ids, test.total, test.limit)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+// Package backup archives the database and CSV file of a workspace, and
+// restores them from the archives.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Names of the files in an archive.
+const (
+	DBFile       = "tasks.db"
+	CSVFile      = "tasks.csv"
+	manifestFile = "manifest.json"
+)
+
+// timeLayout is the time in the name of the archives.
+const timeLayout = "20060102T150405Z"
+
+// namePattern matches the archives made by Create: tasks-<time>[-<label>].tar.gz.
+var namePattern = regexp.MustCompile(`^tasks-(\d{8}T\d{6}Z)(?:-([a-z-]+))?\.tar\.gz$`)
+
+// Manifest describes the files of an archive.
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	// Source is the path of the database the archive was made from.
+	Source string `json:"source,omitempty"`
+	Files  []File `json:"files"`
+}
+
+// File is a file of an archive with its checksum.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Backup is an archive of a backup directory.
+type Backup struct {
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+	// Label is the label given to Create, such as pre-restore.
+	Label string
+}
+
+// Name returns the file name of the archive.
+func (b Backup) Name() string {
+	return filepath.Base(b.Path)
+}
+
+// Create archives a consistent snapshot of db, which may be in use, and the
+// CSV file at csvPath when it exists into dir. The archive is named after
+// now and label, which may be empty, and its checksum is written beside it
+// in the format of sha256sum.
+func Create(dir string, db *sql.DB, csvPath string, now time.Time, label string) (Backup, error) {
+	now = now.UTC().Truncate(time.Second)
+	name := "tasks-" + now.Format(timeLayout)
+	if label != "" {
+		name += "-" + label
+	}
+	path := filepath.Join(dir, name+".tar.gz")
+	if _, err := os.Stat(path); err == nil {
+		return Backup{}, fmt.Errorf("backup %s already exists", path)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return Backup{}, fmt.Errorf("error creating backup directory: %v", err)
+	}
+
+	var source string
+	if err := db.QueryRow(`SELECT file FROM pragma_database_list WHERE name = 'main'`).Scan(&source); err != nil {
+		return Backup{}, fmt.Errorf("error reading the database path: %v", err)
+	}
+	files := make(map[string][]byte)
+	snapshot, err := Snapshot(db)
+	if err != nil {
+		return Backup{}, err
+	}
+	files[DBFile] = snapshot
+	if data, err := os.ReadFile(csvPath); err == nil {
+		files[CSVFile] = data
+	} else if !os.IsNotExist(err) {
+		return Backup{}, fmt.Errorf("error reading CSV file: %v", err)
+	}
+
+	archive, err := writeArchive(files, Manifest{CreatedAt: now, Source: source})
+	if err != nil {
+		return Backup{}, err
+	}
+	// The archive only gets its name once complete, a failed backup leaves
+	// no partial archive behind.
+	if err := writeFileAtomic(path, archive, 0600); err != nil {
+		return Backup{}, fmt.Errorf("error writing backup: %v", err)
+	}
+	sum := sha256.Sum256(archive)
+	checksum := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(checksum), 0600); err != nil {
+		return Backup{}, fmt.Errorf("error writing backup checksum: %v", err)
+	}
+	return Backup{Path: path, CreatedAt: now, Size: int64(len(archive)), Label: label}, nil
+}
+
+// Snapshot returns a consistent copy of db, which may be in use.
+func Snapshot(db *sql.DB) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "tasks-cli-backup")
+	if err != nil {
+		return nil, fmt.Errorf("error creating snapshot: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, DBFile)
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return nil, fmt.Errorf("error creating snapshot: %v", err)
+	}
+	return os.ReadFile(path)
+}
+
+// writeArchive returns a tar.gz archive of files, listed with their checksums
+// in manifest.
+func writeArchive(files map[string][]byte, manifest Manifest) ([]byte, error) {
+	now := manifest.CreatedAt
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sum := sha256.Sum256(files[name])
+		manifest.Files = append(manifest.Files, File{Name: name, Size: int64(len(files[name])), SHA256: hex.EncodeToString(sum[:])})
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := write(manifestFile, manifestData); err != nil {
+		return nil, fmt.Errorf("error writing backup: %v", err)
+	}
+	for _, name := range names {
+		if err := write(name, files[name]); err != nil {
+			return nil, fmt.Errorf("error writing backup: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("error writing backup: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("error writing backup: %v", err)
+	}
+	return archive.Bytes(), nil
+}
+
+// Verify checks an archive: its checksum file when there is one, the
+// checksums of its files and the integrity of its database. The files are
+// returned by name along the manifest.
+func Verify(path string) (Manifest, map[string][]byte, error) {
+	var manifest Manifest
+	archive, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("error reading backup: %v", err)
+	}
+
+	if checksum, err := os.ReadFile(path + ".sha256"); err == nil {
+		sum := sha256.Sum256(archive)
+		fields := strings.Fields(string(checksum))
+		if len(fields) == 0 || fields[0] != hex.EncodeToString(sum[:]) {
+			return manifest, nil, fmt.Errorf("the checksum of %s does not match %s.sha256", filepath.Base(path), filepath.Base(path))
+		}
+	} else if !os.IsNotExist(err) {
+		return manifest, nil, fmt.Errorf("error reading backup checksum: %v", err)
+	}
+
+	files, err := readArchive(archive)
+	if err != nil {
+		return manifest, nil, err
+	}
+	manifestData, ok := files[manifestFile]
+	if !ok {
+		return manifest, nil, fmt.Errorf("the backup has no %s", manifestFile)
+	}
+	delete(files, manifestFile)
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+
+	for _, file := range manifest.Files {
+		data, ok := files[file.Name]
+		if !ok {
+			return manifest, nil, fmt.Errorf("%s is missing from the backup", file.Name)
+		}
+		sum := sha256.Sum256(data)
+		if int64(len(data)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+			return manifest, nil, fmt.Errorf("the checksum of %s does not match the manifest", file.Name)
+		}
+	}
+	if len(files) != len(manifest.Files) {
+		return manifest, nil, errors.New("the backup holds files missing from its manifest")
+	}
+	if _, ok := files[DBFile]; !ok {
+		return manifest, nil, fmt.Errorf("the backup has no %s", DBFile)
+	}
+	if err := checkIntegrity(files[DBFile]); err != nil {
+		return manifest, nil, err
+	}
+	return manifest, files, nil
+}
+
+func readArchive(archive []byte) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	files := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("error reading backup: %v", err)
+		}
+		files[header.Name] = data
+	}
+}
+
+// checkIntegrity runs the integrity check of SQLite on a database.
+func checkIntegrity(data []byte) error {
+	dir, err := os.MkdirTemp("", "tasks-cli-restore")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, DBFile)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return fmt.Errorf("the database of the backup is corrupted: %v", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("the database of the backup is corrupted: %s", result)
+	}
+	return nil
+}
+
+// Restore verifies an archive and replaces the database at dbPath and the CSV
+// file at csvPath with its files. The CSV file is removed when the archive
+// has none. The database must not be open.
+func Restore(path string, dbPath string, csvPath string) error {
+	_, files, err := Verify(path)
+	if err != nil {
+		return err
+	}
+
+	// A journal left by the replaced database would be applied to the
+	// restored one.
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing %s: %v", dbPath+suffix, err)
+		}
+	}
+	if err := writeFileAtomic(dbPath, files[DBFile], 0644); err != nil {
+		return fmt.Errorf("error restoring the database: %v", err)
+	}
+
+	if data, ok := files[CSVFile]; ok {
+		if err := writeFileAtomic(csvPath, data, 0644); err != nil {
+			return fmt.Errorf("error restoring the CSV file: %v", err)
+		}
+	} else if err := os.Remove(csvPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing the CSV file: %v", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes a file through a temporary file renamed over it.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// List returns the archives of dir made by Create, newest first.
+func List(dir string) ([]Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading backup directory: %v", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		match := namePattern.FindStringSubmatch(entry.Name())
+		if match == nil || entry.IsDir() {
+			continue
+		}
+		createdAt, err := time.Parse(timeLayout, match[1])
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, entry.Name()), CreatedAt: createdAt, Size: info.Size(), Label: match[2]})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].Path > backups[j].Path
+		}
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Rotate removes the archives of dir except the newest one of each of the
+// last daily days and of the last weekly weeks that have archives, in local
+// time. The newest archive is always kept, and labeled archives are left
+// alone, see Prune. The removed archives are returned.
+func Rotate(dir string, daily int, weekly int) ([]Backup, error) {
+	backups, err := listLabeled(dir, "")
+	if err != nil || len(backups) == 0 {
+		return nil, err
+	}
+
+	keep := map[string]bool{backups[0].Path: true}
+	days, weeks := make(map[string]bool), make(map[string]bool)
+	for _, b := range backups {
+		local := b.CreatedAt.Local()
+		if day := local.Format("2006-01-02"); !days[day] && len(days) < daily {
+			days[day] = true
+			keep[b.Path] = true
+		}
+		year, number := local.ISOWeek()
+		if week := fmt.Sprintf("%d-%02d", year, number); !weeks[week] && len(weeks) < weekly {
+			weeks[week] = true
+			keep[b.Path] = true
+		}
+	}
+	return removeBackups(backups, keep)
+}
+
+// Prune removes the archives of dir with label except the newest keep ones.
+// The removed archives are returned.
+func Prune(dir string, label string, keep int) ([]Backup, error) {
+	backups, err := listLabeled(dir, label)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool)
+	for i := 0; i < keep && i < len(backups); i++ {
+		kept[backups[i].Path] = true
+	}
+	return removeBackups(backups, kept)
+}
+
+// listLabeled returns the archives of dir with label, newest first.
+func listLabeled(dir string, label string) ([]Backup, error) {
+	all, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []Backup
+	for _, b := range all {
+		if b.Label == label {
+			backups = append(backups, b)
+		}
+	}
+	return backups, nil
+}
+
+// removeBackups removes the archives not in keep with their checksum files.
+func removeBackups(backups []Backup, keep map[string]bool) ([]Backup, error) {
+	var removed []Backup
+	for _, b := range backups {
+		if keep[b.Path] {
+			continue
+		}
+		if err := os.Remove(b.Path); err != nil {
+			return removed, fmt.Errorf("error removing backup: %v", err)
+		}
+		os.Remove(b.Path + ".sha256")
+		removed = append(removed, b)
+	}
+	return removed, nil
+}
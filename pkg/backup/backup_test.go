@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "tasks.db")
+	csvPath := filepath.Join(dir, "tasks.csv")
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE tasks (title TEXT); INSERT INTO tasks VALUES ('Call Acme')`); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	if err := os.WriteFile(csvPath, []byte("ID,TITLE\n1,Call Acme\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := filepath.Join(dir, "backups")
+	created, err := Create(backups, db, csvPath, time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC), "")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.Name() != "tasks-20261018T093000Z.tar.gz" {
+		t.Errorf("Name = %q", created.Name())
+	}
+	if _, err := db.Exec(`DELETE FROM tasks`); err != nil {
+		t.Fatalf("Exec: %v", err)
+	}
+	db.Close()
+	os.Remove(csvPath)
+
+	manifest, _, err := Verify(created.Path)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if manifest.Source != dbPath || len(manifest.Files) != 2 {
+		t.Errorf("manifest = %+v, want the source %s and 2 files", manifest, dbPath)
+	}
+
+	if err := Restore(created.Path, dbPath, csvPath); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	db, err = sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+	var title string
+	if err := db.QueryRow(`SELECT title FROM tasks`).Scan(&title); err != nil || title != "Call Acme" {
+		t.Errorf("restored title = %q, %v", title, err)
+	}
+	if data, err := os.ReadFile(csvPath); err != nil || !strings.Contains(string(data), "Call Acme") {
+		t.Errorf("restored CSV file = %q, %v", data, err)
+	}
+
+	// A damaged archive is refused before anything is replaced.
+	f, err := os.OpenFile(created.Path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("garbage")
+	f.Close()
+	if _, _, err := Verify(created.Path); err == nil {
+		t.Errorf("Verify of a damaged archive succeeded")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"tasks-20261018T130000Z.tar.gz",
+		"tasks-20261018T110000Z.tar.gz",
+		"tasks-20261017T120000Z.tar.gz",
+		"tasks-20261016T120000Z.tar.gz",
+		"tasks-20261009T120000Z.tar.gz",
+		"tasks-20261001T120000Z.tar.gz",
+		"tasks-20261001T110000Z-pre-restore.tar.gz",
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Rotation is in local time.
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+
+	removed, err := Rotate(dir, 2, 3)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	var got []string
+	for _, b := range removed {
+		got = append(got, b.Name())
+	}
+	// 18 and 17 October are the last two days, the weeks of 12 October,
+	// 5 October and 28 September the last three weeks.
+	want := []string{"tasks-20261018T110000Z.tar.gz", "tasks-20261016T120000Z.tar.gz"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("removed %v, want %v", got, want)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"tasks-20261018T120000Z.tar.gz",
+		"tasks-20261018T110000Z-pre-restore.tar.gz",
+		"tasks-20261017T110000Z-pre-restore.tar.gz",
+		"tasks-20261016T110000Z-pre-restore.tar.gz",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := Prune(dir, "pre-restore", 2)
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if len(removed) != 1 || removed[0].Name() != "tasks-20261016T110000Z-pre-restore.tar.gz" {
+		t.Errorf("removed %v, want the oldest pre-restore backup", removed)
+	}
+	if backups, _ := List(dir); len(backups) != 3 {
+		t.Errorf("%d backups left, want 3", len(backups))
+	}
+}
@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/unf6/testing/pkg/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys lists the settings in the order config list shows them.
+var Keys = []string{"workspace", "backend", "output", "date_format", "columns", "colors", "editor", "key_cache", "remote.url", "remote.token"}
+
+// Columns lists the columns of the task table.
+var Columns = []string{"id", "uid", "title", "description", "status", "tracked", "created_at", "updated_at"}
+
+// OptionalColumns lists the columns of the task table that are not shown by
+// default, list --all-workspaces adds the workspace column.
+var OptionalColumns = []string{"workspace", "project", "tags"}
+
+// Config is the content of the config file. The top level settings apply to
+// every profile, a profile overrides the settings it sets.
+type Config struct {
+	Settings `yaml:",inline"`
+	// Profile is the profile used when none is selected with --profile.
+	Profile  string              `yaml:"profile,omitempty"`
+	Profiles map[string]Settings `yaml:"profiles,omitempty"`
+	// Workspaces are the workspaces created besides the default one.
+	Workspaces map[string]Workspace `yaml:"workspaces,omitempty"`
+}
+
+// DefaultWorkspace is the workspace used when none is selected.
+const DefaultWorkspace = "default"
+
+// Workspace is a set of tasks with its own database, CSV file and backups.
+type Workspace struct {
+	Name    string `yaml:"-"`
+	DB      string `yaml:"db,omitempty"`
+	CSV     string `yaml:"csv,omitempty"`
+	Backups string `yaml:"backups,omitempty"`
+}
+
+// Settings are the configurable defaults of the commands.
+type Settings struct {
+	// Workspace is the workspace commands use, the default one when empty.
+	Workspace string `yaml:"workspace,omitempty"`
+	// Backend is the store every command uses without asking: sqlite, csv
+	// or remote. Commands ask when it is empty.
+	Backend string `yaml:"backend,omitempty"`
+	// Output is the default output format of the listing commands, table or json.
+	Output string `yaml:"output,omitempty"`
+	// DateFormat is "relative" or a Go time layout such as "2006-01-02 15:04".
+	DateFormat string `yaml:"date_format,omitempty"`
+	// Columns are the columns of the task table, in order.
+	Columns []string `yaml:"columns,omitempty,flow"`
+	// Colors is auto, always or never; auto colors terminals unless NO_COLOR is set.
+	Colors string `yaml:"colors,omitempty"`
+	// Editor is the command descriptions are edited with.
+	Editor string `yaml:"editor,omitempty"`
+	// KeyCache is how long the key of an encrypted store is remembered after
+	// its last use, such as 15m; 0 asks for the passphrase every time.
+	KeyCache string `yaml:"key_cache,omitempty"`
+	Remote   Remote `yaml:"remote,omitempty"`
+}
+
+// Remote is the tasks-cli server used by the remote backend.
+type Remote struct {
+	URL   string `yaml:"url,omitempty"`
+	Token string `yaml:"token,omitempty"`
+}
+
+// Defaults returns the settings used when neither the config file nor the
+// environment sets them.
+func Defaults() Settings {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	return Settings{
+		Output:     "table",
+		DateFormat: "relative",
+		Columns:    slices.Clone(Columns),
+		Colors:     "auto",
+		Editor:     editor,
+		KeyCache:   "15m",
+	}
+}
+
+// Path returns the location of the config file: TASKS_CLI_CONFIG when set,
+// config.yaml in the config directory otherwise.
+func Path() string {
+	if path := os.Getenv("TASKS_CLI_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(utils.GetConfigDir(), "config.yaml")
+}
+
+// Load reads the config file at path, a missing file is an empty config.
+func Load(path string) (Config, error) {
+	var config Config
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Save writes the config file at path. It is only readable by its owner as
+// it may hold an API token.
+func Save(path string, config Config) error {
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating config directory: %v", err)
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	return nil
+}
+
+// Resolve returns the settings in effect for a profile: the defaults,
+// overridden by the top level settings, the profile, then the TASKS_CLI_*
+// environment variables such as TASKS_CLI_BACKEND or TASKS_CLI_REMOTE_URL.
+// An empty profile selects the default one, see SelectProfile.
+func (c Config) Resolve(profile string) (Settings, error) {
+	profile = c.SelectProfile(profile)
+
+	settings := Defaults()
+	if err := settings.merge(c.Settings); err != nil {
+		return settings, fmt.Errorf("config file: %v", err)
+	}
+	if profile != "" {
+		overrides, ok := c.Profiles[profile]
+		if !ok {
+			return settings, fmt.Errorf("no profile named %q in the config file", profile)
+		}
+		if err := settings.merge(overrides); err != nil {
+			return settings, fmt.Errorf("profile %s: %v", profile, err)
+		}
+	}
+
+	for _, key := range Keys {
+		name := "TASKS_CLI_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			if err := settings.Set(key, value); err != nil {
+				return settings, fmt.Errorf("invalid %s: %v", name, err)
+			}
+		}
+	}
+
+	if settings.Backend == "remote" && settings.Remote.URL == "" {
+		return settings, fmt.Errorf("the remote backend is selected without a remote url, set it with config set remote.url")
+	}
+	return settings, nil
+}
+
+// Workspace returns the paths of a workspace. Paths a workspace does not set
+// are in its directory of the data directory, the default workspace uses the
+// data directory itself.
+func (c Config) Workspace(name string) (Workspace, error) {
+	if name == "" {
+		name = DefaultWorkspace
+	}
+
+	workspace, ok := c.Workspaces[name]
+	if !ok && name != DefaultWorkspace {
+		return workspace, fmt.Errorf("no workspace named %q, create it with workspace create %s", name, name)
+	}
+	workspace.Name = name
+
+	dir := utils.GetDataDir()
+	if name != DefaultWorkspace {
+		dir = filepath.Join(dir, "workspaces", name)
+	}
+	if workspace.DB == "" {
+		workspace.DB = filepath.Join(dir, "tasks.db")
+	}
+	if workspace.CSV == "" {
+		workspace.CSV = filepath.Join(dir, "tasks.csv")
+	}
+	if workspace.Backups == "" {
+		workspace.Backups = filepath.Join(dir, "backups")
+	}
+	return workspace, nil
+}
+
+// Discover looks for the task store of a repository in dir and its parents,
+// the way git finds .git: a .tasks directory holding tasks.db and tasks.csv,
+// or a .tasks.db file with .tasks.csv and .tasks-backups beside it. The
+// workspace is named after the directory it was found in.
+func Discover(dir string) (Workspace, bool) {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, ".tasks")); err == nil && info.IsDir() {
+			return Workspace{
+				Name:    dir,
+				DB:      filepath.Join(dir, ".tasks", "tasks.db"),
+				CSV:     filepath.Join(dir, ".tasks", "tasks.csv"),
+				Backups: filepath.Join(dir, ".tasks", "backups"),
+			}, true
+		}
+		if info, err := os.Stat(filepath.Join(dir, ".tasks.db")); err == nil && !info.IsDir() {
+			return Workspace{
+				Name:    dir,
+				DB:      filepath.Join(dir, ".tasks.db"),
+				CSV:     filepath.Join(dir, ".tasks.csv"),
+				Backups: filepath.Join(dir, ".tasks-backups"),
+			}, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Workspace{}, false
+		}
+		dir = parent
+	}
+}
+
+// WorkspaceNames returns the name of every workspace, the default one first.
+func (c Config) WorkspaceNames() []string {
+	names := []string{DefaultWorkspace}
+	for name := range c.Workspaces {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+	return names
+}
+
+// SelectProfile returns profile, or when it is empty the TASKS_CLI_PROFILE
+// variable or the default profile of the file.
+func (c Config) SelectProfile(profile string) string {
+	if profile == "" {
+		profile = os.Getenv("TASKS_CLI_PROFILE")
+	}
+	if profile == "" {
+		profile = c.Profile
+	}
+	return profile
+}
+
+// merge overrides the settings with the ones set in other.
+func (s *Settings) merge(other Settings) error {
+	for _, key := range Keys {
+		if value, _ := other.Get(key); value != "" {
+			if err := s.Set(key, value); err != nil {
+				return fmt.Errorf("invalid %s: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Get returns the value of a setting, lists are joined with commas.
+func (s Settings) Get(key string) (string, error) {
+	switch key {
+	case "workspace":
+		return s.Workspace, nil
+	case "backend":
+		return s.Backend, nil
+	case "output":
+		return s.Output, nil
+	case "date_format":
+		return s.DateFormat, nil
+	case "columns":
+		return strings.Join(s.Columns, ","), nil
+	case "colors":
+		return s.Colors, nil
+	case "editor":
+		return s.Editor, nil
+	case "key_cache":
+		return s.KeyCache, nil
+	case "remote.url":
+		return s.Remote.URL, nil
+	case "remote.token":
+		return s.Remote.Token, nil
+	}
+	return "", unknownKeyError(key)
+}
+
+// Set validates and changes a setting, an empty value unsets it.
+func (s *Settings) Set(key string, value string) error {
+	value = strings.TrimSpace(value)
+	switch key {
+	case "workspace":
+		s.Workspace = value
+	case "backend":
+		if err := oneOf(value, "sqlite", "csv", "remote"); err != nil {
+			return err
+		}
+		s.Backend = value
+	case "output":
+		if err := oneOf(value, "table", "json"); err != nil {
+			return err
+		}
+		s.Output = value
+	case "date_format":
+		s.DateFormat = value
+	case "columns":
+		var columns []string
+		for _, column := range strings.Split(value, ",") {
+			column = strings.TrimSpace(column)
+			if column == "" {
+				continue
+			}
+			if err := oneOf(column, append(slices.Clone(Columns), OptionalColumns...)...); err != nil {
+				return err
+			}
+			columns = append(columns, column)
+		}
+		s.Columns = columns
+	case "colors":
+		if err := oneOf(value, "auto", "always", "never"); err != nil {
+			return err
+		}
+		s.Colors = value
+	case "editor":
+		s.Editor = value
+	case "key_cache":
+		if value != "" {
+			if _, err := time.ParseDuration(value); err != nil {
+				return fmt.Errorf("%q is not a duration such as 15m or 0", value)
+			}
+		}
+		s.KeyCache = value
+	case "remote.url":
+		s.Remote.URL = value
+	case "remote.token":
+		s.Remote.Token = value
+	default:
+		return unknownKeyError(key)
+	}
+	return nil
+}
+
+func oneOf(value string, valid ...string) error {
+	if value == "" || slices.Contains(valid, value) {
+		return nil
+	}
+	return fmt.Errorf("%q is not one of %s", value, strings.Join(valid, ", "))
+}
+
+func unknownKeyError(key string) error {
+	return fmt.Errorf("unknown setting %q, valid settings are %s", key, strings.Join(Keys, ", "))
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// clearEnv unsets the variables overriding the settings for the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range Keys {
+		t.Setenv("TASKS_CLI_"+strings.ToUpper(strings.ReplaceAll(key, ".", "_")), "")
+	}
+	t.Setenv("TASKS_CLI_PROFILE", "")
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "nano")
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	config := Config{
+		Settings: Settings{Backend: "sqlite", Output: "json", DateFormat: "2006-01-02"},
+		Profile:  "work",
+		Profiles: map[string]Settings{
+			"work": {Backend: "remote", Remote: Remote{URL: "https://tasks.example.com"}, Columns: []string{"id", "title"}},
+			"home": {Backend: "csv", KeyCache: "0"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		env     map[string]string
+		key     string
+		want    string
+	}{
+		{"default", "home", nil, "colors", "auto"},
+		{"default editor from EDITOR", "home", nil, "editor", "nano"},
+		{"file over default", "home", nil, "output", "json"},
+		{"profile over file", "home", nil, "backend", "csv"},
+		{"file kept when the profile does not set it", "home", nil, "date_format", "2006-01-02"},
+		{"default profile of the file", "", nil, "backend", "remote"},
+		{"TASKS_CLI_PROFILE over the default profile", "", map[string]string{"TASKS_CLI_PROFILE": "home"}, "backend", "csv"},
+		{"flag over TASKS_CLI_PROFILE", "home", map[string]string{"TASKS_CLI_PROFILE": "work"}, "backend", "csv"},
+		{"environment over profile", "home", map[string]string{"TASKS_CLI_BACKEND": "sqlite"}, "backend", "sqlite"},
+		{"environment of a nested key", "work", map[string]string{"TASKS_CLI_REMOTE_URL": "http://localhost:8080"}, "remote.url", "http://localhost:8080"},
+		{"environment list", "work", map[string]string{"TASKS_CLI_COLUMNS": "id, status"}, "columns", "id,status"},
+		{"empty variable ignored", "home", map[string]string{"TASKS_CLI_OUTPUT": ""}, "output", "json"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+
+			settings, err := config.Resolve(test.profile)
+			if err != nil {
+				t.Fatalf("Resolve(%q): %v", test.profile, err)
+			}
+			if got, _ := settings.Get(test.key); got != test.want {
+				t.Errorf("%s = %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		profile string
+		env     map[string]string
+		want    string
+	}{
+		{"unknown profile", Config{}, "work", nil, `no profile named "work"`},
+		{"unknown default profile", Config{Profile: "work"}, "", nil, `no profile named "work"`},
+		{"invalid file setting", Config{Settings: Settings{Backend: "mysql"}}, "", nil, "config file: invalid backend"},
+		{"invalid profile setting", Config{Profiles: map[string]Settings{"work": {Colors: "pink"}}}, "work", nil, "profile work: invalid colors"},
+		{"invalid variable", Config{}, "", map[string]string{"TASKS_CLI_KEY_CACHE": "soon"}, "invalid TASKS_CLI_KEY_CACHE"},
+		{"remote without url", Config{Settings: Settings{Backend: "remote"}}, "", nil, "without a remote url"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			if _, err := test.config.Resolve(test.profile); err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Resolve(%q) = %v, want an error containing %q", test.profile, err, test.want)
+			}
+		})
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	tests := []struct {
+		flag, env, file string
+		want            string
+	}{
+		{"", "", "", ""},
+		{"", "", "work", "work"},
+		{"", "home", "work", "home"},
+		{"ci", "home", "work", "ci"},
+	}
+	for _, test := range tests {
+		t.Setenv("TASKS_CLI_PROFILE", test.env)
+		if got := (Config{Profile: test.file}).SelectProfile(test.flag); got != test.want {
+			t.Errorf("SelectProfile(%q) with TASKS_CLI_PROFILE=%q and profile %q = %q, want %q", test.flag, test.env, test.file, got, test.want)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	settings := Settings{Backend: "sqlite", Output: "json", Columns: []string{"id"}, Remote: Remote{URL: "https://a.example.com", Token: "a"}}
+	if err := settings.merge(Settings{Output: "table", Columns: []string{"title", "status"}, Remote: Remote{Token: "b"}}); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	want := Settings{Backend: "sqlite", Output: "table", Columns: []string{"title", "status"}, Remote: Remote{URL: "https://a.example.com", Token: "b"}}
+	if settings.Backend != want.Backend || settings.Output != want.Output || !slices.Equal(settings.Columns, want.Columns) || settings.Remote != want.Remote {
+		t.Errorf("merged settings = %+v, want %+v", settings, want)
+	}
+
+	if err := settings.merge(Settings{Output: "xml"}); err == nil || !strings.Contains(err.Error(), "invalid output") {
+		t.Errorf("merge of an invalid output: %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+		err        string
+	}{
+		{"backend", "csv", "csv", ""},
+		{"backend", " remote ", "remote", ""},
+		{"backend", "mysql", "", `"mysql" is not one of sqlite, csv, remote`},
+		{"backend", "", "", ""},
+		{"output", "json", "json", ""},
+		{"output", "yaml", "", "is not one of"},
+		{"colors", "never", "never", ""},
+		{"colors", "sometimes", "", "is not one of"},
+		{"columns", "id, title,,status", "id,title,status", ""},
+		{"columns", "workspace,project,tags,id", "workspace,project,tags,id", ""},
+		{"columns", "id,priority", "", `"priority" is not one of`},
+		{"key_cache", "1h30m", "1h30m", ""},
+		{"key_cache", "0", "0", ""},
+		{"key_cache", "30", "", "is not a duration"},
+		{"date_format", "2006-01-02 15:04", "2006-01-02 15:04", ""},
+		{"remote.url", "https://tasks.example.com", "https://tasks.example.com", ""},
+		{"remote.token", "tcli_secret", "tcli_secret", ""},
+		{"workspace", "work", "work", ""},
+		{"editor", "code --wait", "code --wait", ""},
+		{"theme", "dark", "", `unknown setting "theme"`},
+	}
+	for _, test := range tests {
+		var settings Settings
+		err := settings.Set(test.key, test.value)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Set(%q, %q) = %v, want an error containing %q", test.key, test.value, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Set(%q, %q): %v", test.key, test.value, err)
+			continue
+		}
+		if got, _ := settings.Get(test.key); got != test.want {
+			t.Errorf("Set(%q, %q) stored %q, want %q", test.key, test.value, got, test.want)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks-cli", "config.yaml")
+	if config, err := Load(path); err != nil || config.Profile != "" {
+		t.Fatalf("Load of a missing file = %+v, %v", config, err)
+	}
+
+	saved := Config{
+		Settings: Settings{Backend: "remote", Remote: Remote{URL: "https://tasks.example.com", Token: "tcli_secret"}},
+		Profile:  "work",
+		Profiles: map[string]Settings{"work": {Columns: []string{"id", "title"}}},
+	}
+	if err := Save(path, saved); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Backend != "remote" || loaded.Remote != saved.Remote || loaded.Profile != "work" || !slices.Equal(loaded.Profiles["work"].Columns, []string{"id", "title"}) {
+		t.Errorf("loaded %+v, want %+v", loaded, saved)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	mkdir := func(path ...string) string {
+		dir := filepath.Join(append([]string{root}, path...)...)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	// root/dir holds a .tasks directory, root/dir/file a .tasks.db file and
+	// root/plain neither.
+	mkdir("dir", ".tasks")
+	dir := filepath.Join(root, "dir")
+	file := mkdir("dir", "file")
+	if err := os.WriteFile(filepath.Join(file, ".tasks.db"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mkdir("dir", "file", "nested", "deeper")
+	mkdir("dir", "src", "pkg")
+	mkdir("plain")
+
+	inDir := Workspace{Name: dir, DB: filepath.Join(dir, ".tasks", "tasks.db"), CSV: filepath.Join(dir, ".tasks", "tasks.csv"), Backups: filepath.Join(dir, ".tasks", "backups")}
+	inFile := Workspace{Name: file, DB: filepath.Join(file, ".tasks.db"), CSV: filepath.Join(file, ".tasks.csv"), Backups: filepath.Join(file, ".tasks-backups")}
+	tests := []struct {
+		start string
+		want  Workspace
+		found bool
+	}{
+		{dir, inDir, true},
+		{filepath.Join(dir, "src", "pkg"), inDir, true},
+		{file, inFile, true},
+		// The nearest store wins.
+		{filepath.Join(file, "nested", "deeper"), inFile, true},
+		{filepath.Join(root, "plain"), Workspace{}, false},
+	}
+	for _, test := range tests {
+		workspace, found := Discover(test.start)
+		if found != test.found || workspace != test.want {
+			t.Errorf("Discover(%s) = %+v, %v, want %+v, %v", test.start, workspace, found, test.want, test.found)
+		}
+	}
+}
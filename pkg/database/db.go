@@ -2,36 +2,75 @@ package database
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/unf6/testing/pkg/utils"
 )
 
-var db *sql.DB
-
-func ConnectDB() {
+var (
+	db *sql.DB
+	// path is the database GetDB opens on first use, see UseDB.
+	path string
+)
 
+// ConnectDB opens the database at dbPath as the database of GetDB,
+// creating its directory if needed.
+func ConnectDB(dbPath string) {
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		log.Fatalf("Failed to create the database directory: %v", err)
+	}
 
-	configDir := filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
+	var err error
+	db, err = Open(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open the database: %v", err)
+	}
+}
 
+// UseDB selects the database at dbPath for GetDB, which only opens it when
+// it is first used so that commands not needing it do not create it.
+func UseDB(dbPath string) {
+	db, path = nil, dbPath
+}
 
-	if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err = os.MkdirAll(configDir, 0755)
-		if err != nil {
-			log.Fatalf("Failed to create config directory: %v", err)
-		}
+// Open opens the SQLite database at path, creating and migrating its tables
+// as needed. Foreign keys are enforced on every connection, so that rows are
+// deleted along the ones they reference.
+func Open(path string) (*sql.DB, error) {
+	conn, err := sql.Open("sqlite3", path+"?_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
 	}
+	if err := migrate(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
 
-	dbPath := filepath.Join(configDir, "tasks.db")
-
-	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+// OpenReadOnly opens the SQLite database at path for reading only, without
+// creating nor migrating it, e.g. to read another workspace.
+func OpenReadOnly(path string) (*sql.DB, error) {
+	uri := &url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: "mode=ro"}
+	conn, err := sql.Open("sqlite3", uri.String())
 	if err != nil {
-		log.Fatalf("Error opening database: %v", err)
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error opening database: %v", err)
 	}
+	return conn, nil
+}
 
+// migrate creates the tables missing from db and upgrades older ones.
+func migrate(db *sql.DB) error {
 	createTableQuery := `
 	CREATE TABLE IF NOT EXISTS tasks (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -39,15 +78,246 @@ func ConnectDB() {
 		description TEXT,
 		status TEXT NOT NULL DEFAULT 'pending',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		uid TEXT,
+		deleted_at DATETIME,
+		archived_at DATETIME
 	);	
 	`
 	if _, err := db.Exec(createTableQuery); err != nil {
-		log.Fatalf("Error creating table: %v", err)
+		return fmt.Errorf("error creating table: %v", err)
+	}
+
+	createHistoryTableQuery := `
+	CREATE TABLE IF NOT EXISTS task_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		backend TEXT NOT NULL,
+		task_id INTEGER NOT NULL,
+		task_uid TEXT,
+		action TEXT NOT NULL,
+		field TEXT,
+		old_value TEXT,
+		new_value TEXT,
+		actor TEXT,
+		changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_task_history_task ON task_history(backend, task_id);
+	`
+	if _, err := db.Exec(createHistoryTableQuery); err != nil {
+		return fmt.Errorf("error creating history table: %v", err)
+	}
+
+	createJournalTablesQuery := `
+	CREATE TABLE IF NOT EXISTS operations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		command TEXT NOT NULL,
+		undone INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS operation_changes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		operation_id INTEGER NOT NULL REFERENCES operations(id) ON DELETE CASCADE,
+		backend TEXT NOT NULL,
+		before TEXT,
+		after TEXT
+	);
+	`
+	if _, err := db.Exec(createJournalTablesQuery); err != nil {
+		return fmt.Errorf("error creating journal tables: %v", err)
+	}
+
+	if err := migrateUIDs(db); err != nil {
+		return fmt.Errorf("error migrating task identifiers: %v", err)
+	}
+	if err := ensureColumn(db, "tasks", "deleted_at", "DATETIME"); err != nil {
+		return fmt.Errorf("error migrating the trash column: %v", err)
+	}
+	if err := ensureColumn(db, "tasks", "archived_at", "DATETIME"); err != nil {
+		return fmt.Errorf("error migrating the archive column: %v", err)
+	}
+	if err := ensureColumn(db, "tasks", "project", "TEXT"); err != nil {
+		return fmt.Errorf("error migrating the project column: %v", err)
+	}
+	if err := ensureColumn(db, "tasks", "tags", "TEXT"); err != nil {
+		return fmt.Errorf("error migrating the tags column: %v", err)
+	}
+
+	createTimeEntriesTableQuery := `
+	CREATE TABLE IF NOT EXISTS time_entries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		backend TEXT NOT NULL,
+		task_id INTEGER NOT NULL,
+		task_uid TEXT NOT NULL,
+		started_at DATETIME NOT NULL,
+		ended_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS idx_time_entries_task ON time_entries(task_uid);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries((ended_at IS NULL)) WHERE ended_at IS NULL;
+	`
+	if _, err := db.Exec(createTimeEntriesTableQuery); err != nil {
+		return fmt.Errorf("error creating time entries table: %v", err)
+	}
+
+	createSettingsTableQuery := `
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`
+	if _, err := db.Exec(createSettingsTableQuery); err != nil {
+		return fmt.Errorf("error creating settings table: %v", err)
+	}
+
+	createTokensTableQuery := `
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		prefix TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		last_used_at DATETIME,
+		revoked_at DATETIME
+	);
+	`
+	if _, err := db.Exec(createTokensTableQuery); err != nil {
+		return fmt.Errorf("error creating API tokens table: %v", err)
+	}
+	if err := migrateTokenNames(db); err != nil {
+		return fmt.Errorf("error migrating API token names: %v", err)
+	}
+
+	createWebhookTablesQuery := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		url TEXT NOT NULL,
+		events TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS webhook_outbox (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at DATETIME NOT NULL,
+		last_error TEXT,
+		delivered_at DATETIME,
+		failed_at DATETIME,
+		claimed_until DATETIME,
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_webhook_outbox_pending ON webhook_outbox(next_attempt_at) WHERE delivered_at IS NULL AND failed_at IS NULL;
+	`
+	if _, err := db.Exec(createWebhookTablesQuery); err != nil {
+		return fmt.Errorf("error creating webhook tables: %v", err)
+	}
+	return nil
+}
+
+// GetSetting returns the value stored for key, or an empty string when unset.
+func GetSetting(key string) (string, error) {
+	var value string
+	err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return value, err
+}
+
+// SetSetting stores value for key, an empty value removes the setting.
+func SetSetting(key string, value string) error {
+	if value == "" {
+		_, err := db.Exec(`DELETE FROM settings WHERE key = ?`, key)
+		return err
+	}
+	_, err := db.Exec(`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+// migrateUIDs adds the uid column to databases created before it existed and
+// gives every task without one a ULID derived from its creation time.
+func migrateUIDs(db *sql.DB) error {
+	if err := ensureColumn(db, "tasks", "uid", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_uid ON tasks(uid)`); err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`SELECT id, created_at FROM tasks WHERE uid IS NULL OR uid = ''`)
+	if err != nil {
+		return err
+	}
+	missing := map[int]time.Time{}
+	for rows.Next() {
+		var id int
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		missing[id] = createdAt
+	}
+	rows.Close()
+
+	for id, createdAt := range missing {
+		if _, err := db.Exec(`UPDATE tasks SET uid = ? WHERE id = ?`, utils.NewULID(createdAt), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateTokenNames makes the names of the active API tokens unique, the
+// tokens named like an older active one get their ID appended.
+func migrateTokenNames(db *sql.DB) error {
+	_, err := db.Exec(`
+	UPDATE api_tokens SET name = name || '-' || id
+	WHERE revoked_at IS NULL AND EXISTS (
+		SELECT 1 FROM api_tokens AS older
+		WHERE older.name = api_tokens.name AND older.revoked_at IS NULL AND older.id < api_tokens.id
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_active_name ON api_tokens(name) WHERE revoked_at IS NULL;
+	`)
+	return err
+}
+
+// ensureColumn adds a column to tables created before it existed.
+func ensureColumn(db *sql.DB, table string, column string, definition string) error {
+	exists, err := hasColumn(db, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// hasColumn reports whether the table has a column with the given name.
+func hasColumn(db *sql.DB, table string, column string) (bool, error) {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return false, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
 }
 
 func GetDB() *sql.DB {
+	if db == nil && path != "" {
+		ConnectDB(path)
+	}
 	return db
 }
 
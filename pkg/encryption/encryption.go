@@ -0,0 +1,220 @@
+// Package encryption encrypts task stores and exports with a passphrase: keys
+// are derived with Argon2id and data is sealed with XChaCha20-Poly1305.
+package encryption
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Argon2id parameters of the key derivation, version 1 of the formats.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	saltSize     = 16
+)
+
+// fileMagic starts encrypted files, followed by the format version.
+var fileMagic = []byte("TASKSENC")
+
+const fileVersion = 1
+
+// fieldPrefix starts encrypted values stored in a database column.
+const fieldPrefix = "enc1:"
+
+// checkText is sealed to tell whether a passphrase is the right one.
+const checkText = "tasks-cli"
+
+var (
+	// ErrWrongPassphrase is returned when data cannot be decrypted with the
+	// key derived from a passphrase.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted data")
+	// ErrLocked is returned when encrypted data is read without a key.
+	ErrLocked = errors.New("the data is encrypted and no passphrase was given")
+)
+
+// KeyFunc returns the key derived from the passphrase of encrypted data with
+// salt, checked with verify, see Keyring.Key.
+type KeyFunc func(salt []byte, verify func(*Key) error) (*Key, error)
+
+// Key is a key derived from a passphrase and a salt. Methods of a nil Key
+// leave data in plaintext.
+type Key struct {
+	salt []byte
+	raw  []byte
+	aead cipher.AEAD
+}
+
+// NewKey derives a key from passphrase with a new random salt.
+func NewKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, errors.New("the passphrase cannot be empty")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("error generating salt: %v", err)
+	}
+	return DeriveKey(passphrase, salt), nil
+}
+
+// DeriveKey derives the key of passphrase and salt.
+func DeriveKey(passphrase string, salt []byte) *Key {
+	key, _ := keyFromRaw(salt, argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, chacha20poly1305.KeySize))
+	return key
+}
+
+func keyFromRaw(salt []byte, raw []byte) (*Key, error) {
+	aead, err := chacha20poly1305.NewX(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{salt: bytes.Clone(salt), raw: raw, aead: aead}, nil
+}
+
+// Salt returns the salt the key was derived with.
+func (k *Key) Salt() []byte {
+	return bytes.Clone(k.salt)
+}
+
+// seal encrypts plaintext with a random nonce it is prefixed with.
+func (k *Key) seal(plaintext []byte, additional []byte) []byte {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(plaintext)+k.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("error generating nonce: %v", err))
+	}
+	return k.aead.Seal(nonce, nonce, plaintext, additional)
+}
+
+// open decrypts data sealed with seal.
+func (k *Key) open(sealed []byte, additional []byte) ([]byte, error) {
+	if len(sealed) < k.aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	plaintext, err := k.aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// Check returns a value Verify accepts for this key only, to be stored along
+// data encrypted value by value.
+func (k *Key) Check() string {
+	return base64.StdEncoding.EncodeToString(k.seal([]byte(checkText), nil))
+}
+
+// Verify returns ErrWrongPassphrase unless check was returned by Check of
+// the same key.
+func (k *Key) Verify(check string) error {
+	sealed, err := base64.StdEncoding.DecodeString(check)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	plaintext, err := k.open(sealed, nil)
+	if err != nil || string(plaintext) != checkText {
+		return ErrWrongPassphrase
+	}
+	return nil
+}
+
+// Location is the table, column and row a value encrypted with EncryptString
+// is stored in. It is authenticated along the value, which cannot be
+// decrypted once moved to another location.
+type Location struct {
+	Table  string
+	Column string
+	// Row identifies the row, such as the UID of a task.
+	Row string
+}
+
+func (l Location) additionalData() []byte {
+	return []byte(l.Table + "\x00" + l.Column + "\x00" + l.Row)
+}
+
+// EncryptString encrypts a value stored in a database column at location at.
+// Empty values are kept as is.
+func (k *Key) EncryptString(value string, at Location) string {
+	if k == nil || value == "" {
+		return value
+	}
+	return fieldPrefix + base64.RawStdEncoding.EncodeToString(k.seal([]byte(value), at.additionalData()))
+}
+
+// DecryptString decrypts a value encrypted with EncryptString at the same
+// location, plaintext values are returned as is.
+func (k *Key) DecryptString(value string, at Location) (string, error) {
+	if !IsEncryptedString(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrLocked
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, fieldPrefix))
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	plaintext, err := k.open(sealed, at.additionalData())
+	return string(plaintext), err
+}
+
+// IsEncryptedString reports whether value was encrypted with EncryptString.
+func IsEncryptedString(value string) bool {
+	return strings.HasPrefix(value, fieldPrefix)
+}
+
+// Encrypt encrypts the content of a file. The salt of the key is kept in the
+// header so that the file can be decrypted with the passphrase alone.
+func (k *Key) Encrypt(plaintext []byte) []byte {
+	header := fileHeader(k.salt)
+	return append(header, k.seal(plaintext, header)...)
+}
+
+// IsEncrypted reports whether data is the content of an encrypted file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, fileMagic)
+}
+
+// Decrypt decrypts the content of a file encrypted with Key.Encrypt, asking
+// keys for the key of its salt. The key is returned along the plaintext to
+// write the file back.
+func Decrypt(data []byte, keys KeyFunc) ([]byte, *Key, error) {
+	headerSize := len(fileMagic) + 1 + saltSize
+	if !IsEncrypted(data) || len(data) < headerSize {
+		return nil, nil, errors.New("not an encrypted file")
+	}
+	if version := data[len(fileMagic)]; version != fileVersion {
+		return nil, nil, fmt.Errorf("unsupported encryption format version %d", version)
+	}
+	if keys == nil {
+		return nil, nil, ErrLocked
+	}
+
+	header, sealed := data[:headerSize], data[headerSize:]
+	key, err := keys(header[len(fileMagic)+1:], func(key *Key) error {
+		_, err := key.open(sealed, header)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := key.open(sealed, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, key, nil
+}
+
+func fileHeader(salt []byte) []byte {
+	header := append(bytes.Clone(fileMagic), fileVersion)
+	return append(header, salt...)
+}
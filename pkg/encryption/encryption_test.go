@@ -0,0 +1,123 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestEncryptFile(t *testing.T) {
+	key, err := NewKey("correct horse")
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	data := key.Encrypt([]byte("ID,TITLE\n1,Call Acme\n"))
+	if !IsEncrypted(data) || bytes.Contains(data, []byte("Acme")) {
+		t.Fatalf("encrypted data %q is not encrypted", data)
+	}
+
+	keys := &Keyring{Passphrase: func() (string, error) { return "correct horse", nil }}
+	plaintext, decryptKey, err := Decrypt(data, keys.Key)
+	if err != nil || string(plaintext) != "ID,TITLE\n1,Call Acme\n" {
+		t.Fatalf("Decrypt = %q, %v", plaintext, err)
+	}
+	if !bytes.Equal(decryptKey.Salt(), key.Salt()) {
+		t.Errorf("Decrypt returned a key with another salt")
+	}
+
+	wrong := &Keyring{Passphrase: func() (string, error) { return "wrong", nil }}
+	if _, _, err := Decrypt(data, wrong.Key); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Decrypt with a wrong passphrase: %v, want ErrWrongPassphrase", err)
+	}
+	if _, _, err := Decrypt(data, nil); !errors.Is(err, ErrLocked) {
+		t.Errorf("Decrypt without keys: %v, want ErrLocked", err)
+	}
+}
+
+func TestEncryptString(t *testing.T) {
+	key := DeriveKey("correct horse", []byte("0123456789abcdef"))
+	at := Location{Table: "tasks", Column: "title", Row: "01J0000000000000000000000A"}
+
+	value := key.EncryptString("Call Acme", at)
+	if !IsEncryptedString(value) || value == key.EncryptString("Call Acme", at) {
+		t.Errorf("EncryptString = %q, want a value encrypted with a random nonce", value)
+	}
+	if got, err := key.DecryptString(value, at); err != nil || got != "Call Acme" {
+		t.Errorf("DecryptString = %q, %v", got, err)
+	}
+	if got, err := key.DecryptString("plain", at); err != nil || got != "plain" {
+		t.Errorf("DecryptString of plaintext = %q, %v", got, err)
+	}
+
+	// A value moved to another location is refused.
+	moved := []Location{
+		{Table: "task_history", Column: "title", Row: at.Row},
+		{Table: "tasks", Column: "description", Row: at.Row},
+		{Table: "tasks", Column: "title", Row: "01J0000000000000000000000B"},
+	}
+	for _, other := range moved {
+		if _, err := key.DecryptString(value, other); !errors.Is(err, ErrWrongPassphrase) {
+			t.Errorf("DecryptString at %+v: %v, want ErrWrongPassphrase", other, err)
+		}
+	}
+
+	var none *Key
+	if got := none.EncryptString("Call Acme", at); got != "Call Acme" {
+		t.Errorf("EncryptString of a nil key = %q, want the plaintext", got)
+	}
+	if _, err := none.DecryptString(value, at); !errors.Is(err, ErrLocked) {
+		t.Errorf("DecryptString of a nil key: %v, want ErrLocked", err)
+	}
+
+	if err := key.Verify(key.Check()); err != nil {
+		t.Errorf("Verify of its own check: %v", err)
+	}
+	if err := DeriveKey("wrong", key.Salt()).Verify(key.Check()); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Verify with a wrong passphrase: %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestKeyringCache(t *testing.T) {
+	dir := t.TempDir()
+	key, err := NewKey("correct horse")
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+	check := key.Check()
+	verify := func(k *Key) error { return k.Verify(check) }
+
+	first := &Keyring{Dir: dir, TTL: time.Minute, Passphrase: func() (string, error) { return "correct horse", nil }}
+	if _, err := first.Key(key.Salt(), verify); err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+
+	// A later command of the session finds the key without the passphrase.
+	asked := false
+	second := &Keyring{Dir: dir, TTL: time.Minute, Passphrase: func() (string, error) {
+		asked = true
+		return "", errors.New("no terminal")
+	}}
+	if _, err := second.Key(key.Salt(), verify); err != nil || asked {
+		t.Fatalf("Key from the cache: %v, passphrase asked: %v", err, asked)
+	}
+
+	locked := &Keyring{Dir: t.TempDir(), TTL: time.Minute, Passphrase: second.Passphrase}
+	if _, err := locked.Unlocked(key.Salt(), verify); !errors.Is(err, ErrLocked) {
+		t.Errorf("Unlocked without a cached key: %v, want ErrLocked", err)
+	}
+	if _, err := second.Unlocked(key.Salt(), verify); err != nil {
+		t.Errorf("Unlocked with a cached key: %v", err)
+	}
+
+	if err := second.Forget(); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	if _, err := second.Key(key.Salt(), verify); err == nil || !asked {
+		t.Errorf("Key after Forget: %v, passphrase asked: %v", err, asked)
+	}
+	asked = false
+	if _, err := second.Unlocked(key.Salt(), verify); !errors.Is(err, ErrLocked) || asked {
+		t.Errorf("Unlocked after Forget: %v, passphrase asked: %v", err, asked)
+	}
+}
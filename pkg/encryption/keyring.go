@@ -0,0 +1,153 @@
+package encryption
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Keyring derives the keys of encrypted data from a passphrase asked at most
+// once, and caches them as files of Dir for TTL after their last use so that
+// the commands of a session do not ask for it again. The cache is disabled
+// when Dir is empty or TTL is 0.
+type Keyring struct {
+	Dir string
+	TTL time.Duration
+	// Passphrase asks for the passphrase when no cached key fits.
+	Passphrase func() (string, error)
+
+	mu         sync.Mutex
+	passphrase string
+	keys       map[string]*Key
+}
+
+// Key returns the key of salt, from the cache or derived from the passphrase.
+// verify tells whether a key decrypts the data, ErrWrongPassphrase is
+// returned when the passphrase does not; it is asked again on the next call.
+func (r *Keyring) Key(salt []byte, verify func(*Key) error) (*Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := keyID(salt)
+	if key := r.known(id, salt, verify); key != nil {
+		return key, nil
+	}
+
+	if r.passphrase == "" {
+		if r.Passphrase == nil {
+			return nil, ErrLocked
+		}
+		passphrase, err := r.Passphrase()
+		if err != nil {
+			return nil, err
+		}
+		r.passphrase = passphrase
+	}
+	key := DeriveKey(r.passphrase, salt)
+	if err := verify(key); err != nil {
+		r.passphrase = ""
+		return nil, err
+	}
+	r.remember(id, key)
+	return key, nil
+}
+
+// Unlocked returns the key of salt like Key when the keyring knows it or it
+// is cached, without asking for the passphrase: ErrLocked is returned
+// otherwise. It suits work that can wait for a command unlocking the key.
+func (r *Keyring) Unlocked(salt []byte, verify func(*Key) error) (*Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key := r.known(keyID(salt), salt, verify); key != nil {
+		return key, nil
+	}
+	return nil, ErrLocked
+}
+
+// known returns the key of salt given to this keyring or cached, nil when
+// there is none.
+func (r *Keyring) known(id string, salt []byte, verify func(*Key) error) *Key {
+	if key, ok := r.keys[id]; ok {
+		return key
+	}
+	if key := r.cached(id, salt); key != nil && verify(key) == nil {
+		r.remember(id, key)
+		return key
+	}
+	return nil
+}
+
+// Add caches a key created from a new passphrase.
+func (r *Keyring) Add(key *Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remember(keyID(key.salt), key)
+}
+
+// Forget removes every cached key, and the passphrase given to this keyring.
+func (r *Keyring) Forget() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.passphrase = ""
+	r.keys = nil
+	if r.Dir == "" {
+		return nil
+	}
+	return os.RemoveAll(r.Dir)
+}
+
+// remember keeps the key for the process and caches it when enabled, which
+// restarts its TTL.
+func (r *Keyring) remember(id string, key *Key) {
+	if r.keys == nil {
+		r.keys = make(map[string]*Key)
+	}
+	r.keys[id] = key
+
+	if r.Dir == "" || r.TTL <= 0 {
+		return
+	}
+	// A failure to cache the key only means the passphrase is asked again.
+	if err := os.MkdirAll(r.Dir, 0700); err == nil {
+		os.WriteFile(filepath.Join(r.Dir, id), key.raw, 0600)
+	}
+}
+
+// cached returns the cached key of salt, nil when it is missing or expired.
+func (r *Keyring) cached(id string, salt []byte) *Key {
+	if r.Dir == "" || r.TTL <= 0 {
+		return nil
+	}
+	path := filepath.Join(r.Dir, id)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if time.Since(info.ModTime()) > r.TTL || info.Mode().Perm() != 0600 {
+		os.Remove(path)
+		return nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil || len(raw) != chacha20poly1305.KeySize {
+		return nil
+	}
+	key, err := keyFromRaw(salt, raw)
+	if err != nil {
+		return nil
+	}
+	return key
+}
+
+// keyID names the cache file of the key of salt.
+func keyID(salt []byte) string {
+	sum := sha256.Sum256(salt)
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,305 @@
+// Package tasks gives Go programs access to tasks-cli tasks.
+//
+// Service manages the tasks of a local Store, the SQLite database or CSV
+// file the CLI uses, without prompting or exiting:
+//
+//	store, err := tasks.OpenSQLite(path)
+//	service := tasks.NewService(store)
+//	page, err := service.List(ctx, tasks.ListOptions{Status: []string{"pending"}})
+//
+// Changes made through a Service are not recorded in the history, undo
+// journal or webhook outbox of the CLI unless the store has an OnChange hook.
+//
+// Client talks to the JSON API of a tasks-cli server started with
+// "tasks-cli serve":
+//
+//	client := tasks.NewClient("http://127.0.0.1:8080", tasks.WithToken(token))
+//	task, err := client.Create(ctx, models.Task{Title: "Write the report"})
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unf6/testing/models"
+)
+
+// MaxPageSize is the largest page the server returns.
+const MaxPageSize = 500
+
+// Errors an *APIError matches with errors.Is, following its status code.
+var (
+	ErrInvalid      = errors.New("tasks: invalid request")
+	ErrUnauthorized = errors.New("tasks: missing or invalid API token")
+	ErrForbidden    = errors.New("tasks: API token not allowed to change tasks")
+	ErrNotFound     = errors.New("tasks: task not found")
+)
+
+// APIError is an error response of the server. Fields holds the validation
+// error of each invalid field.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Fields     map[string]string
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("tasks: server responded %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		message += fmt.Sprintf(", %s %s", field, e.Fields[field])
+	}
+	return message
+}
+
+// Is reports whether the status code of the error matches target.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalid:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// View selects tasks by their place in the lifecycle.
+type View string
+
+const (
+	ViewActive   View = "active"
+	ViewArchived View = "archived"
+	ViewTrashed  View = "trashed"
+	ViewAll      View = "all"
+)
+
+// ListOptions filters and paginates List. Zero values are left to the
+// server: active tasks of any status, 50 per page.
+type ListOptions struct {
+	Status []string
+	// Query matches the title or description, case insensitive.
+	Query  string
+	View   View
+	Limit  int
+	Offset int
+}
+
+func (o ListOptions) values() url.Values {
+	values := url.Values{}
+	if len(o.Status) > 0 {
+		values.Set("status", strings.Join(o.Status, ","))
+	}
+	if o.Query != "" {
+		values.Set("q", o.Query)
+	}
+	if o.View != "" {
+		values.Set("view", string(o.View))
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		values.Set("offset", strconv.Itoa(o.Offset))
+	}
+	return values
+}
+
+// Page is a page of tasks; Total counts every task matching the filters.
+type Page struct {
+	Tasks  []models.Task `json:"tasks"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+// Client calls the API of a tasks-cli server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken authenticates requests with an API token created with
+// "tasks-cli token create".
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient sends requests with client instead of a client with a
+// 30 second timeout.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// NewClient returns a client of the server at baseURL, e.g. http://127.0.0.1:8080.
+func NewClient(baseURL string, options ...Option) *Client {
+	client := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// List returns a page of the tasks matching the options.
+func (c *Client) List(ctx context.Context, options ListOptions) (*Page, error) {
+	var page Page
+	if err := c.do(ctx, http.MethodGet, "/api/tasks?"+options.values().Encode(), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// All iterates over every task matching the options, fetching the pages as
+// needed from options.Offset on. Iteration stops after the first error.
+func (c *Client) All(ctx context.Context, options ListOptions) iter.Seq2[models.Task, error] {
+	return func(yield func(models.Task, error) bool) {
+		if options.Limit == 0 {
+			options.Limit = MaxPageSize
+		}
+		for {
+			page, err := c.List(ctx, options)
+			if err != nil {
+				yield(models.Task{}, err)
+				return
+			}
+			for _, task := range page.Tasks {
+				if !yield(task, nil) {
+					return
+				}
+			}
+			options.Offset += len(page.Tasks)
+			if len(page.Tasks) == 0 || options.Offset >= page.Total {
+				return
+			}
+		}
+	}
+}
+
+// Get returns a task by integer ID or UID prefix, archived and trashed
+// tasks included.
+func (c *Client) Get(ctx context.Context, id string) (models.Task, error) {
+	var task models.Task
+	err := c.do(ctx, http.MethodGet, "/api/tasks/"+url.PathEscape(id), nil, &task)
+	return task, err
+}
+
+// Create adds a task with the title, description, status, project and tags
+// of task and returns it as stored. The status defaults to pending; the
+// server sets the ID, UID and timestamps.
+func (c *Client) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	fields := map[string]interface{}{
+		"title":       task.Title,
+		"description": task.Description,
+		"status":      task.Status,
+		"project":     task.Project,
+		"tags":        task.Tags,
+	}
+	var created models.Task
+	err := c.do(ctx, http.MethodPost, "/api/tasks", fields, &created)
+	return created, err
+}
+
+// Update saves the title, description, status, project, tags, DeletedAt and
+// ArchivedAt of the task with task.ID, and returns the updated task.
+func (c *Client) Update(ctx context.Context, task models.Task) (models.Task, error) {
+	fields := map[string]interface{}{
+		"title":       task.Title,
+		"description": task.Description,
+		"status":      task.Status,
+		"project":     task.Project,
+		"tags":        task.Tags,
+		"deleted_at":  task.DeletedAt,
+		"archived_at": task.ArchivedAt,
+	}
+	var updated models.Task
+	err := c.do(ctx, http.MethodPatch, "/api/tasks/"+strconv.Itoa(task.ID), fields, &updated)
+	return updated, err
+}
+
+// Delete moves a task to the trash.
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/tasks/"+url.PathEscape(id), nil, nil)
+}
+
+// Purge deletes a task for good, whether it is in the trash or not.
+func (c *Client) Purge(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/api/tasks/"+url.PathEscape(id)+"?purge=true", nil, nil)
+}
+
+// do sends a request and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: response.StatusCode}
+		var errorBody struct {
+			Error  string            `json:"error"`
+			Fields map[string]string `json:"fields"`
+		}
+		if json.NewDecoder(response.Body).Decode(&errorBody) == nil {
+			apiErr.Message, apiErr.Fields = errorBody.Error, errorBody.Fields
+		}
+		return apiErr
+	}
+	if out == nil || response.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(out)
+}
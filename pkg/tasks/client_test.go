@@ -0,0 +1,194 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/api"
+	"github.com/unf6/testing/pkg/tasks"
+)
+
+const testToken = "tcli_test"
+
+// testServer is the API of the serve command on a new database.
+type testServer struct {
+	*httptest.Server
+	mu sync.Mutex
+	// queries records the query string of every list request.
+	queries []string
+}
+
+func newTestServer(t *testing.T, count int) (*testServer, *tasks.Client) {
+	t.Helper()
+	previous := log.Writer()
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(previous) })
+
+	store, err := tasks.OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { store.DB.Close() })
+
+	service := tasks.NewService(store)
+	for i := 0; i < count; i++ {
+		if _, err := service.Create(context.Background(), models.Task{Title: fmt.Sprintf("Task %d", i+1)}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	server := &api.Server{
+		Service: service,
+		Authenticate: func(secret string) (*api.Token, error) {
+			if secret != testToken {
+				return nil, nil
+			}
+			return &api.Token{Name: "test", Write: true}, nil
+		},
+	}
+	handler := server.Handler()
+
+	test := &testServer{}
+	test.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == "/api/tasks" {
+			test.mu.Lock()
+			test.queries = append(test.queries, r.URL.RawQuery)
+			test.mu.Unlock()
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(test.Close)
+
+	return test, tasks.NewClient(test.URL+"/", tasks.WithToken(testToken))
+}
+
+func TestListSendsFilters(t *testing.T) {
+	server, client := newTestServer(t, 3)
+
+	page, err := client.List(context.Background(), tasks.ListOptions{
+		Status: []string{"pending", "completed"},
+		Query:  "task",
+		View:   tasks.ViewAll,
+		Limit:  2,
+		Offset: 1,
+	})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+
+	if want := "limit=2&offset=1&q=task&status=pending%2Ccompleted&view=all"; server.queries[0] != want {
+		t.Errorf("query = %q, want %q", server.queries[0], want)
+	}
+	if page.Total != 3 || len(page.Tasks) != 2 || page.Tasks[0].ID != 2 {
+		t.Errorf("page = %+v, want tasks 2 and 3 of 3", page)
+	}
+}
+
+func TestAllFetchesEveryPage(t *testing.T) {
+	server, client := newTestServer(t, 7)
+
+	var ids []int
+	for task, err := range client.All(context.Background(), tasks.ListOptions{Limit: 3}) {
+		if err != nil {
+			t.Fatalf("All: %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	if len(ids) != 7 || ids[0] != 1 || ids[6] != 7 {
+		t.Errorf("ids = %v, want 1 to 7", ids)
+	}
+	if len(server.queries) != 3 {
+		t.Errorf("%d list requests, want 3", len(server.queries))
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	server, client := newTestServer(t, 7)
+
+	for task := range client.All(context.Background(), tasks.ListOptions{Limit: 3}) {
+		if task.ID == 2 {
+			break
+		}
+	}
+	if len(server.queries) != 1 {
+		t.Errorf("%d list requests, want 1", len(server.queries))
+	}
+}
+
+func TestCreateGetUpdateDelete(t *testing.T) {
+	_, client := newTestServer(t, 0)
+	ctx := context.Background()
+
+	created, err := client.Create(ctx, models.Task{Title: "Write the report", Description: "Q3"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.ID != 1 || created.Status != "pending" {
+		t.Errorf("created = %+v, want ID 1 pending", created)
+	}
+
+	created.Status = "completed"
+	updated, err := client.Update(ctx, created)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Status != "completed" || updated.Description != "Q3" {
+		t.Errorf("updated = %+v, want completed with its description", updated)
+	}
+
+	if err := client.Delete(ctx, "1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	trashed, err := client.Get(ctx, "1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if trashed.DeletedAt == nil {
+		t.Error("Delete did not move the task to the trash")
+	}
+
+	if err := client.Purge(ctx, "1"); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if _, err := client.Get(ctx, "1"); !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("Get after Purge: %v, want ErrNotFound", err)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	server, client := newTestServer(t, 1)
+	ctx := context.Background()
+
+	_, err := client.Get(ctx, "42")
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("Get of a missing task: %v, want ErrNotFound", err)
+	}
+
+	_, err = client.Create(ctx, models.Task{})
+	var apiErr *tasks.APIError
+	if !errors.As(err, &apiErr) || !errors.Is(err, tasks.ErrInvalid) {
+		t.Fatalf("Create without title: %v, want an ErrInvalid APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Fields["title"] != "is required" {
+		t.Errorf("APIError = %+v, want 422 with a title error", apiErr)
+	}
+	if !strings.Contains(err.Error(), "title is required") {
+		t.Errorf("message %q does not mention the invalid field", err.Error())
+	}
+
+	_, err = tasks.NewClient(server.URL).List(ctx, tasks.ListOptions{})
+	if !errors.Is(err, tasks.ErrUnauthorized) || errors.Is(err, tasks.ErrForbidden) {
+		t.Errorf("List without token: %v, want ErrUnauthorized only", err)
+	}
+}
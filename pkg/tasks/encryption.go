@@ -0,0 +1,318 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/encryption"
+)
+
+// Settings of an encrypted database: the salt of its key and a check of the
+// passphrase, see encryption.Key.Check.
+const (
+	saltSetting  = "encryption.salt"
+	checkSetting = "encryption.check"
+)
+
+// encryptedColumns lists the columns holding task content, encrypted value by
+// value in an encrypted database. The history, the undo journal and the
+// webhook outbox keep copies of the content.
+// The values are bound to the row expression, see encryption.Location.
+var encryptedColumns = []struct {
+	table   string
+	columns []string
+	row     string
+}{
+	{"tasks", []string{"title", "description", "project", "tags"}, "uid"},
+	{"task_history", []string{"old_value", "new_value"}, "COALESCE(task_uid, '')"},
+	{"operation_changes", []string{"before", "after"}, "operation_id"},
+	{"webhook_outbox", []string{"payload"}, "webhook_id"},
+}
+
+// taskLocation returns the location of column in the row of the task uid.
+func taskLocation(column string, uid string) encryption.Location {
+	return encryption.Location{Table: "tasks", Column: column, Row: uid}
+}
+
+// DatabaseKey returns the key the task content of db is encrypted with, asked
+// from keys, or nil when db is not encrypted.
+func DatabaseKey(db *sql.DB, keys encryption.KeyFunc) (*encryption.Key, error) {
+	var encodedSalt, check string
+	err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, saltSetting).Scan(&encodedSalt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading the encryption settings: %v", err)
+	}
+	if err := db.QueryRow(`SELECT value FROM settings WHERE key = ?`, checkSetting).Scan(&check); err != nil {
+		return nil, fmt.Errorf("error reading the encryption settings: %v", err)
+	}
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s setting: %v", saltSetting, err)
+	}
+
+	if keys == nil {
+		return nil, fmt.Errorf("the database is encrypted: %w", encryption.ErrLocked)
+	}
+	return keys(salt, func(key *encryption.Key) error {
+		return key.Verify(check)
+	})
+}
+
+// DatabaseEncrypted reports whether the task content of db is encrypted,
+// without asking for its key.
+func DatabaseEncrypted(db *sql.DB) (bool, error) {
+	var encrypted bool
+	if err := db.QueryRow(`SELECT EXISTS(SELECT 1 FROM settings WHERE key = ?)`, saltSetting).Scan(&encrypted); err != nil {
+		return false, fmt.Errorf("error reading the encryption settings: %v", err)
+	}
+	return encrypted, nil
+}
+
+// cipher returns the key of the database, nil when it is not encrypted.
+func (s *SQLiteStore) cipher() (*encryption.Key, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.unlocked {
+		key, err := DatabaseKey(s.DB, s.Keys)
+		if err != nil {
+			return nil, err
+		}
+		s.key, s.unlocked = key, true
+	}
+	return s.key, nil
+}
+
+// decrypt decrypts the title, description, project and tags of a task read
+// from the database.
+func (s *SQLiteStore) decrypt(task *models.Task) error {
+	tags := strings.Join(task.Tags, ",")
+	fields := []*string{&task.Title, &task.Description, &task.Project, &tags}
+	if !slices.ContainsFunc(fields, func(field *string) bool { return encryption.IsEncryptedString(*field) }) {
+		return nil
+	}
+	key, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	for i, column := range []string{"title", "description", "project", "tags"} {
+		if *fields[i], err = key.DecryptString(*fields[i], taskLocation(column, task.UID)); err != nil {
+			return fmt.Errorf("error decrypting task %d: %w", task.ID, err)
+		}
+	}
+	task.Tags = splitTags(tags)
+	return nil
+}
+
+// Encrypt encrypts the task content of the database with key, replacing the
+// key it was encrypted with, or decrypts it when key is nil. The pages the
+// previous content was in are zeroed and the file is vacuumed, so that it
+// cannot be recovered from the database file.
+func (s *SQLiteStore) Encrypt(key *encryption.Key) error {
+	old, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	// The pragma only applies to the connection it is run on.
+	ctx := context.Background()
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error opening the database: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA secure_delete = ON`); err != nil {
+		return fmt.Errorf("error enabling secure delete: %v", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range encryptedColumns {
+		if err := reencryptColumns(tx, table.table, table.columns, table.row, old, key); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM settings WHERE key IN (?, ?)`, saltSetting, checkSetting); err != nil {
+		return fmt.Errorf("error saving the encryption settings: %v", err)
+	}
+	if key != nil {
+		if _, err := tx.Exec(`INSERT INTO settings (key, value) VALUES (?, ?), (?, ?)`,
+			saltSetting, base64.StdEncoding.EncodeToString(key.Salt()), checkSetting, key.Check()); err != nil {
+			return fmt.Errorf("error saving the encryption settings: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+
+	s.mu.Lock()
+	s.key, s.unlocked = key, true
+	s.mu.Unlock()
+
+	if _, err := conn.ExecContext(ctx, `VACUUM`); err != nil {
+		return fmt.Errorf("error vacuuming the database: %v", err)
+	}
+	return nil
+}
+
+// reencryptColumns decrypts the columns of every row of table with from and
+// encrypts them with to, at the location of the row expression.
+func reencryptColumns(tx *sql.Tx, table string, columns []string, rowExpr string, from *encryption.Key, to *encryption.Key) error {
+	rows, err := tx.Query(fmt.Sprintf(`SELECT id, %s, %s FROM %s`, rowExpr, strings.Join(columns, ", "), table))
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", table, err)
+	}
+	type row struct {
+		id     int64
+		at     string
+		values []sql.NullString
+	}
+	var all []row
+	for rows.Next() {
+		r := row{values: make([]sql.NullString, len(columns))}
+		dest := []interface{}{&r.id, &r.at}
+		for i := range r.values {
+			dest = append(dest, &r.values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return fmt.Errorf("error reading %s: %v", table, err)
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading %s: %v", table, err)
+	}
+
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = column + " = ?"
+	}
+	update := fmt.Sprintf(`UPDATE %s SET %s WHERE id = ?`, table, strings.Join(assignments, ", "))
+	for _, r := range all {
+		values := make([]interface{}, len(columns), len(columns)+1)
+		for i, value := range r.values {
+			if !value.Valid {
+				continue
+			}
+			at := encryption.Location{Table: table, Column: columns[i], Row: r.at}
+			plaintext, err := from.DecryptString(value.String, at)
+			if err != nil {
+				return fmt.Errorf("error decrypting %s %d: %v", table, r.id, err)
+			}
+			values[i] = to.EncryptString(plaintext, at)
+		}
+		if _, err := tx.Exec(update, append(values, r.id)...); err != nil {
+			return fmt.Errorf("error updating %s %d: %v", table, r.id, err)
+		}
+	}
+	return nil
+}
+
+// Encrypt encrypts the file with key, replacing the key it was encrypted
+// with, or decrypts it when key is nil. A missing file is created so that the
+// tasks added later are encrypted.
+func (s *CSVStore) Encrypt(key *encryption.Key) error {
+	staged, err := s.StageEncrypt(key)
+	if err != nil {
+		return err
+	}
+	return staged.Commit()
+}
+
+// StagedEncryption is the content of a CSV file encrypted with another key,
+// written beside the file until Commit replaces the file with it.
+type StagedEncryption struct {
+	store *CSVStore
+	path  string
+	key   *encryption.Key
+}
+
+// StageEncrypt writes the tasks of the file encrypted with key, or decrypted
+// when key is nil, to a temporary file beside it. The file is left as is
+// until Commit, so that it can be changed along other stores: Abort removes
+// the temporary file when one of them fails.
+func (s *CSVStore) StageEncrypt(key *encryption.Key) (*StagedEncryption, error) {
+	tasks, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	content, err := encodeCSV(tasks, key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("error writing to CSV: %v", err)
+	}
+	staged := &StagedEncryption{store: s, path: file.Name(), key: key}
+	_, err = file.Write(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(staged.path, 0644)
+	}
+	if err != nil {
+		staged.Abort()
+		return nil, fmt.Errorf("error writing to CSV: %v", err)
+	}
+	return staged, nil
+}
+
+// Commit replaces the file with its encrypted content.
+func (s *StagedEncryption) Commit() error {
+	if err := os.Rename(s.path, s.store.Path); err != nil {
+		s.Abort()
+		return fmt.Errorf("error writing to CSV: %v", err)
+	}
+	s.store.key = s.key
+	return nil
+}
+
+// Abort removes the encrypted content, leaving the file as is.
+func (s *StagedEncryption) Abort() {
+	os.Remove(s.path)
+}
+
+// keys returns the key the file was last read or written with when it
+// matches, asking Keys otherwise.
+func (s *CSVStore) keys(salt []byte, verify func(*encryption.Key) error) (*encryption.Key, error) {
+	if s.key != nil && bytes.Equal(s.key.Salt(), salt) && verify(s.key) == nil {
+		return s.key, nil
+	}
+	if s.Keys == nil {
+		return nil, encryption.ErrLocked
+	}
+	return s.Keys(salt, verify)
+}
+
+// Encrypted reports whether the file is encrypted, without decrypting it.
+func (s *CSVStore) Encrypted() (bool, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading CSV file: %v", err)
+	}
+	return encryption.IsEncrypted(data), nil
+}
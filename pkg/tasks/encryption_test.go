@@ -0,0 +1,156 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/encryption"
+)
+
+func TestEncryptedStores(t *testing.T) {
+	sqlite, err := OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { sqlite.DB.Close() })
+	csvPath := filepath.Join(t.TempDir(), "tasks.csv")
+
+	stores := map[string]interface {
+		Store
+		Encrypt(*encryption.Key) error
+	}{
+		"sqlite": sqlite,
+		"csv":    &CSVStore{Path: csvPath},
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			service := NewService(store)
+			if _, err := service.Create(ctx, models.Task{Title: "Call Acme", Description: "Renewal", Project: "Acme", Tags: []string{"sales", "q3"}}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			key, err := encryption.NewKey("correct horse")
+			if err != nil {
+				t.Fatalf("NewKey: %v", err)
+			}
+			if err := store.Encrypt(key); err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if _, err := service.Create(ctx, models.Task{Title: "Call Globex"}); err != nil {
+				t.Fatalf("Create once encrypted: %v", err)
+			}
+
+			// A new store reads the tasks back with the passphrase only.
+			reopened := reopen(store, &encryption.Keyring{Passphrase: func() (string, error) { return "correct horse", nil }})
+			page, err := NewService(reopened).List(ctx, ListOptions{})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if page.Total != 2 || page.Tasks[0].Title != "Call Acme" || page.Tasks[0].Description != "Renewal" ||
+				page.Tasks[0].Project != "Acme" || strings.Join(page.Tasks[0].Tags, ",") != "sales,q3" || page.Tasks[1].Title != "Call Globex" {
+				t.Errorf("page = %+v, want both tasks decrypted", page)
+			}
+
+			if store == sqlite {
+				var project, tags string
+				sqlite.DB.QueryRow(`SELECT project, tags FROM tasks WHERE id = 1`).Scan(&project, &tags)
+				if !encryption.IsEncryptedString(project) || !encryption.IsEncryptedString(tags) {
+					t.Errorf("stored project %q and tags %q, want them encrypted", project, tags)
+				}
+
+				// A value copied to another row or column does not decrypt.
+				var first, second string
+				sqlite.DB.QueryRow(`SELECT title FROM tasks WHERE id = 1`).Scan(&first)
+				sqlite.DB.QueryRow(`SELECT title FROM tasks WHERE id = 2`).Scan(&second)
+				for _, moved := range []string{
+					`UPDATE tasks SET title = (SELECT title FROM tasks WHERE id = 1) WHERE id = 2`,
+					`UPDATE tasks SET title = (SELECT description FROM tasks WHERE id = 1) WHERE id = 1`,
+				} {
+					if _, err := sqlite.DB.Exec(moved); err != nil {
+						t.Fatal(err)
+					}
+					if _, err := sqlite.List(); !errors.Is(err, encryption.ErrWrongPassphrase) {
+						t.Errorf("List once %q: %v, want ErrWrongPassphrase", moved, err)
+					}
+				}
+				sqlite.DB.Exec(`UPDATE tasks SET title = CASE id WHEN 1 THEN ? ELSE ? END`, first, second)
+			}
+
+			wrong := reopen(store, &encryption.Keyring{Passphrase: func() (string, error) { return "wrong", nil }})
+			if _, err := wrong.List(); !errors.Is(err, encryption.ErrWrongPassphrase) {
+				t.Errorf("List with a wrong passphrase: %v, want ErrWrongPassphrase", err)
+			}
+
+			if err := store.Encrypt(nil); err != nil {
+				t.Fatalf("Encrypt(nil): %v", err)
+			}
+			if _, err := reopen(store, nil).List(); err != nil {
+				t.Errorf("List once decrypted without a passphrase: %v", err)
+			}
+		})
+	}
+
+	if data, err := os.ReadFile(csvPath); err != nil || !bytes.Contains(data, []byte("Call Acme")) {
+		t.Errorf("decrypted CSV file = %q, %v", data, err)
+	}
+}
+
+// reopen returns a new store on the file of store, asking keys for its key.
+func reopen(store Store, keys *encryption.Keyring) Store {
+	var keyFunc encryption.KeyFunc
+	if keys != nil {
+		keyFunc = keys.Key
+	}
+	switch store := store.(type) {
+	case *SQLiteStore:
+		return &SQLiteStore{DB: store.DB, Keys: keyFunc}
+	case *CSVStore:
+		return &CSVStore{Path: store.Path, Keys: keyFunc}
+	}
+	return nil
+}
+
+func TestStageEncrypt(t *testing.T) {
+	dir := t.TempDir()
+	store := &CSVStore{Path: filepath.Join(dir, "tasks.csv")}
+	if _, err := NewService(store).Create(context.Background(), models.Task{Title: "Call Acme"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	key, err := encryption.NewKey("correct horse")
+	if err != nil {
+		t.Fatalf("NewKey: %v", err)
+	}
+
+	// The file keeps its plaintext until the staged content is committed.
+	staged, err := store.StageEncrypt(key)
+	if err != nil {
+		t.Fatalf("StageEncrypt: %v", err)
+	}
+	if data, _ := os.ReadFile(store.Path); !bytes.Contains(data, []byte("Call Acme")) {
+		t.Errorf("CSV file once staged = %q, want it unchanged", data)
+	}
+	staged.Abort()
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory once aborted holds %d files, want only the CSV file", len(entries))
+	}
+
+	if staged, err = store.StageEncrypt(key); err != nil {
+		t.Fatalf("StageEncrypt: %v", err)
+	}
+	if err := staged.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if data, _ := os.ReadFile(store.Path); bytes.Contains(data, []byte("Call Acme")) {
+		t.Errorf("CSV file once committed = %q, want it encrypted", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory once committed holds %d files, want only the CSV file", len(entries))
+	}
+}
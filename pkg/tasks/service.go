@@ -0,0 +1,345 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unf6/testing/models"
+)
+
+// ValidationError holds the validation error of each invalid field of a
+// task. It matches ErrInvalid with errors.Is.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field+" "+e.Fields[field])
+	}
+	sort.Strings(fields)
+	return "invalid task: " + strings.Join(fields, ", ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// AmbiguousError is returned when a UID prefix matches several tasks. It
+// matches ErrInvalid with errors.Is.
+type AmbiguousError struct {
+	Ref     string
+	Matches int
+}
+
+func (e AmbiguousError) Error() string {
+	return fmt.Sprintf("ID prefix %s is ambiguous, it matches %d tasks", e.Ref, e.Matches)
+}
+
+func (e AmbiguousError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// Validate returns a *ValidationError when the task has no title or an
+// unknown status.
+func Validate(task models.Task) error {
+	fields := make(map[string]string)
+	if strings.TrimSpace(task.Title) == "" {
+		fields["title"] = "is required"
+	}
+	if !slices.Contains(Statuses, task.Status) {
+		fields["status"] = "must be one of " + strings.Join(Statuses, ", ")
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// NormalizeTags trims and lowercases tags, splits the ones holding commas
+// and drops the empty and repeated ones.
+func NormalizeTags(tags []string) []string {
+	var normalized []string
+	for _, value := range tags {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" && !slices.Contains(normalized, tag) {
+				normalized = append(normalized, tag)
+			}
+		}
+	}
+	return normalized
+}
+
+// Service reads and changes the tasks of a store without a terminal: its
+// methods never prompt nor exit, they return errors matching ErrNotFound
+// and ErrInvalid with errors.Is. A Service is not safe for concurrent use.
+//
+//	store, err := tasks.OpenSQLite("tasks.db")
+//	service := tasks.NewService(store)
+//	task, err := service.Create(ctx, models.Task{Title: "Write the report"})
+type Service struct {
+	store Store
+}
+
+// NewService returns a service managing the tasks of store.
+func NewService(store Store) *Service {
+	return &Service{store: store}
+}
+
+// List returns a page of the tasks matching the options. A zero Limit
+// returns every matching task from Offset on.
+func (s *Service) List(ctx context.Context, options ListOptions) (*Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string)
+	if options.Limit < 0 {
+		fields["limit"] = "must not be negative"
+	}
+	if options.Offset < 0 {
+		fields["offset"] = "must not be negative"
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	tasks, err := s.view(options.View)
+	if err != nil {
+		return nil, err
+	}
+
+	search := strings.ToLower(options.Query)
+	matching := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if len(options.Status) > 0 && !slices.Contains(options.Status, task.Status) {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(task.Title), search) &&
+			!strings.Contains(strings.ToLower(task.Description), search) {
+			continue
+		}
+		matching = append(matching, task)
+	}
+
+	page := &Page{Tasks: []models.Task{}, Total: len(matching), Limit: options.Limit, Offset: options.Offset}
+	if options.Offset < len(matching) {
+		end := len(matching)
+		if options.Limit > 0 && options.Offset+options.Limit < end {
+			end = options.Offset + options.Limit
+		}
+		page.Tasks = matching[options.Offset:end]
+	}
+	return page, nil
+}
+
+// view returns the tasks of a view, the active ones when it is empty.
+func (s *Service) view(view View) ([]models.Task, error) {
+	switch view {
+	case "", ViewActive:
+		return s.store.List()
+	case ViewArchived:
+		return s.store.Archived()
+	case ViewTrashed:
+		return s.store.Trashed()
+	case ViewAll:
+		var tasks []models.Task
+		for _, list := range []func() ([]models.Task, error){s.store.List, s.store.Archived, s.store.Trashed} {
+			found, err := list()
+			if err != nil {
+				return nil, err
+			}
+			tasks = append(tasks, found...)
+		}
+		sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+		return tasks, nil
+	}
+	return nil, &ValidationError{Fields: map[string]string{"view": "must be one of active, archived, trashed, all"}}
+}
+
+// Get returns a task by integer ID or UID prefix, archived and trashed
+// tasks included.
+func (s *Service) Get(ctx context.Context, id string) (models.Task, error) {
+	if number, err := strconv.Atoi(id); err == nil {
+		// Digits not matching an ID may still be the prefix of a UID.
+		if task, err := s.ByID(ctx, number); !errors.Is(err, ErrNotFound) {
+			return task, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	tasks, err := s.view(ViewAll)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return Match(tasks, id)
+}
+
+// ByID returns the task with the integer ID, archived and trashed tasks
+// included.
+func (s *Service) ByID(ctx context.Context, id int) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Task{}, err
+	}
+	if lookup, ok := s.store.(IDLookup); ok {
+		return lookup.ByID(id)
+	}
+
+	tasks, err := s.view(ViewAll)
+	if err != nil {
+		return models.Task{}, err
+	}
+	for _, task := range tasks {
+		if task.ID == id {
+			return task, nil
+		}
+	}
+	return models.Task{}, NotFoundError{strconv.Itoa(id)}
+}
+
+// ByUID returns the tasks with the UIDs by UID, archived and trashed tasks
+// included. The UIDs of deleted tasks are left out.
+func (s *Service) ByUID(ctx context.Context, uids []string) (map[string]models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var found []models.Task
+	var err error
+	if lookup, ok := s.store.(UIDLookup); ok {
+		found, err = lookup.ByUID(uids)
+	} else {
+		found, err = s.view(ViewAll)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make(map[string]models.Task, len(uids))
+	for _, task := range found {
+		if slices.Contains(uids, task.UID) {
+			tasks[task.UID] = task
+		}
+	}
+	return tasks, nil
+}
+
+// Create adds a task and returns it as stored. The status defaults to
+// pending; the ID, UID and timestamps are kept when set and free.
+func (s *Service) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return task, err
+	}
+
+	task.Title = strings.TrimSpace(task.Title)
+	task.Project = strings.TrimSpace(task.Project)
+	task.Tags = NormalizeTags(task.Tags)
+	if task.Status == "" {
+		task.Status = "pending"
+	}
+	if err := Validate(task); err != nil {
+		return task, err
+	}
+
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now().UTC()
+	}
+	if task.UpdatedAt.IsZero() {
+		task.UpdatedAt = task.CreatedAt
+	}
+	return s.store.Add(task)
+}
+
+// Update saves the title, description, status, project, tags, DeletedAt and
+// ArchivedAt of the task with task.ID, and returns the updated task.
+func (s *Service) Update(ctx context.Context, task models.Task) (models.Task, error) {
+	stored, err := s.ByID(ctx, task.ID)
+	if err != nil {
+		return stored, err
+	}
+	return s.save(stored, func(stored *models.Task) {
+		stored.Title = strings.TrimSpace(task.Title)
+		stored.Description = task.Description
+		stored.Status = task.Status
+		stored.Project = strings.TrimSpace(task.Project)
+		stored.Tags = NormalizeTags(task.Tags)
+		stored.DeletedAt = task.DeletedAt
+		stored.ArchivedAt = task.ArchivedAt
+	})
+}
+
+// Delete moves a task to the trash.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	_, err := s.change(ctx, id, func(task *models.Task) {
+		if task.DeletedAt == nil {
+			now := time.Now().UTC()
+			task.DeletedAt = &now
+		}
+	})
+	return err
+}
+
+// Restore takes a task out of the trash.
+func (s *Service) Restore(ctx context.Context, id string) error {
+	_, err := s.change(ctx, id, func(task *models.Task) {
+		task.DeletedAt = nil
+	})
+	return err
+}
+
+// Archive moves a task to the archive.
+func (s *Service) Archive(ctx context.Context, id string) error {
+	_, err := s.change(ctx, id, func(task *models.Task) {
+		if task.ArchivedAt == nil {
+			now := time.Now().UTC()
+			task.ArchivedAt = &now
+		}
+	})
+	return err
+}
+
+// Unarchive takes a task out of the archive.
+func (s *Service) Unarchive(ctx context.Context, id string) error {
+	_, err := s.change(ctx, id, func(task *models.Task) {
+		task.ArchivedAt = nil
+	})
+	return err
+}
+
+// Purge deletes a task for good, whether it is in the trash or not.
+func (s *Service) Purge(ctx context.Context, id string) error {
+	task, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.store.Delete(task.ID)
+}
+
+// change applies edit to the task with the ID or UID prefix id and saves it
+// when it is still valid.
+func (s *Service) change(ctx context.Context, id string, edit func(*models.Task)) (models.Task, error) {
+	task, err := s.Get(ctx, id)
+	if err != nil {
+		return task, err
+	}
+	return s.save(task, edit)
+}
+
+// save applies edit to task and saves it when it is still valid.
+func (s *Service) save(task models.Task, edit func(*models.Task)) (models.Task, error) {
+	edit(&task)
+	if err := Validate(task); err != nil {
+		return task, err
+	}
+	task.UpdatedAt = time.Now().UTC()
+	return task, s.store.Update(task)
+}
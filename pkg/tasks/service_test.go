@@ -0,0 +1,279 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/unf6/testing/models"
+)
+
+func newTestServices(t *testing.T) map[string]*Service {
+	t.Helper()
+
+	sqlite, err := OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { sqlite.DB.Close() })
+
+	return map[string]*Service{
+		"sqlite": NewService(sqlite),
+		"csv":    NewService(&CSVStore{Path: filepath.Join(t.TempDir(), "tasks.csv")}),
+	}
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	for name, service := range newTestServices(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			created, err := service.Create(ctx, models.Task{Title: "  Write the report ", Description: "Q3"})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if created.ID != 1 || created.UID == "" || created.Title != "Write the report" || created.Status != "pending" {
+				t.Errorf("created = %+v, want ID 1 pending with a trimmed title", created)
+			}
+
+			got, err := service.Get(ctx, created.UID[:10])
+			if err != nil || got.ID != created.ID {
+				t.Fatalf("Get by UID prefix = %+v, %v", got, err)
+			}
+
+			created.Status = "completed"
+			updated, err := service.Update(ctx, created)
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if updated.Status != "completed" || updated.Description != "Q3" || !updated.UpdatedAt.After(created.UpdatedAt) {
+				t.Errorf("updated = %+v, want completed with its description", updated)
+			}
+
+			if err := service.Archive(ctx, "1"); err != nil {
+				t.Fatalf("Archive: %v", err)
+			}
+			assertView(t, service, ViewActive, 0)
+			assertView(t, service, ViewArchived, 1)
+
+			if err := service.Delete(ctx, "1"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			assertView(t, service, ViewArchived, 0)
+			assertView(t, service, ViewTrashed, 1)
+
+			if err := service.Restore(ctx, "1"); err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+			if err := service.Unarchive(ctx, "1"); err != nil {
+				t.Fatalf("Unarchive: %v", err)
+			}
+			assertView(t, service, ViewActive, 1)
+
+			if err := service.Purge(ctx, "1"); err != nil {
+				t.Fatalf("Purge: %v", err)
+			}
+			assertView(t, service, ViewAll, 0)
+		})
+	}
+}
+
+func TestServiceProjectAndTags(t *testing.T) {
+	for name, service := range newTestServices(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			created, err := service.Create(ctx, models.Task{Title: "Call Acme", Project: " Acme ", Tags: []string{"Billing, urgent", " billing", ""}})
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			got, err := service.Get(ctx, "1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.Project != "Acme" || !slices.Equal(got.Tags, []string{"billing", "urgent"}) || !slices.Equal(created.Tags, got.Tags) {
+				t.Errorf("stored task = %+v, want the Acme project and the billing and urgent tags", got)
+			}
+
+			got.Project, got.Tags = "", nil
+			if _, err := service.Update(ctx, got); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if got, _ := service.Get(ctx, "1"); got.Project != "" || got.Tags != nil {
+				t.Errorf("updated task = %+v, want no project nor tags", got)
+			}
+		})
+	}
+}
+
+func assertView(t *testing.T, service *Service, view View, want int) {
+	t.Helper()
+
+	page, err := service.List(context.Background(), ListOptions{View: view})
+	if err != nil {
+		t.Fatalf("List %s: %v", view, err)
+	}
+	if page.Total != want {
+		t.Errorf("%d %s tasks, want %d", page.Total, view, want)
+	}
+}
+
+func TestServiceListFilters(t *testing.T) {
+	service := newTestServices(t)["csv"]
+	ctx := context.Background()
+
+	for _, task := range []models.Task{
+		{Title: "Write the report", Status: "pending"},
+		{Title: "Review", Description: "the REPORT draft", Status: "in-progress"},
+		{Title: "Book flights", Status: "completed"},
+		{Title: "Report expenses", Status: "completed"},
+	} {
+		if _, err := service.Create(ctx, task); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	page, err := service.List(ctx, ListOptions{Query: "report", Status: []string{"pending", "in-progress"}})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 2 || page.Tasks[0].ID != 1 || page.Tasks[1].ID != 2 {
+		t.Errorf("page = %+v, want tasks 1 and 2", page)
+	}
+
+	page, err = service.List(ctx, ListOptions{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Total != 4 || len(page.Tasks) != 2 || page.Tasks[0].ID != 2 {
+		t.Errorf("page = %+v, want tasks 2 and 3 of 4", page)
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	service := newTestServices(t)["sqlite"]
+	ctx := context.Background()
+
+	_, err := service.Create(ctx, models.Task{Status: "later"})
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("Create of an invalid task: %v, want an ErrInvalid ValidationError", err)
+	}
+	if invalid.Fields["title"] != "is required" || invalid.Fields["status"] == "" {
+		t.Errorf("fields = %v, want title and status errors", invalid.Fields)
+	}
+
+	if _, err := service.Get(ctx, "42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a missing task: %v, want ErrNotFound", err)
+	}
+	if err := service.Delete(ctx, "42"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete of a missing task: %v, want ErrNotFound", err)
+	}
+	if _, err := service.List(ctx, ListOptions{View: "done"}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("List of an unknown view: %v, want ErrInvalid", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := service.Create(canceled, models.Task{Title: "Too late"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Create with a canceled context: %v, want context.Canceled", err)
+	}
+}
+
+func TestServiceByUID(t *testing.T) {
+	for name, service := range newTestServices(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var uids []string
+			for _, title := range []string{"Call Acme", "Call Globex", "Call Initech"} {
+				task, err := service.Create(ctx, models.Task{Title: title})
+				if err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+				uids = append(uids, task.UID)
+			}
+			if err := service.Delete(ctx, "3"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+
+			found, err := service.ByUID(ctx, []string{uids[0], uids[2], "01J0000000000000000000000Z"})
+			if err != nil {
+				t.Fatalf("ByUID: %v", err)
+			}
+			if len(found) != 2 || found[uids[0]].Title != "Call Acme" || found[uids[2]].Title != "Call Initech" {
+				t.Errorf("ByUID = %+v, want Call Acme and the trashed Call Initech", found)
+			}
+		})
+	}
+}
+
+func TestServiceByID(t *testing.T) {
+	for name, service := range newTestServices(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			for _, title := range []string{"Call Acme", "Call Globex"} {
+				if _, err := service.Create(ctx, models.Task{Title: title}); err != nil {
+					t.Fatalf("Create: %v", err)
+				}
+			}
+			if err := service.Delete(ctx, "2"); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+
+			tests := []struct {
+				id    int
+				title string
+				err   error
+			}{
+				{id: 1, title: "Call Acme"},
+				{id: 2, title: "Call Globex"},
+				{id: 3, err: ErrNotFound},
+			}
+			for _, tt := range tests {
+				task, err := service.ByID(ctx, tt.id)
+				if !errors.Is(err, tt.err) || task.Title != tt.title {
+					t.Errorf("ByID(%d) = %q, %v, want %q, %v", tt.id, task.Title, err, tt.title, tt.err)
+				}
+			}
+		})
+	}
+}
+
+// listCountingStore counts the reads of every task of the store.
+type listCountingStore struct {
+	*SQLiteStore
+	lists int
+}
+
+func (s *listCountingStore) List() ([]models.Task, error) {
+	s.lists++
+	return s.SQLiteStore.List()
+}
+
+func TestServiceLooksUpIDs(t *testing.T) {
+	sqlite, err := OpenSQLite(filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	defer sqlite.DB.Close()
+	store := &listCountingStore{SQLiteStore: sqlite}
+	service := NewService(store)
+
+	ctx := context.Background()
+	task, err := service.Create(ctx, models.Task{Title: "Call Acme"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	task.Title = "Call Acme back"
+	if _, err := service.Update(ctx, task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := service.Purge(ctx, "1"); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+	if store.lists != 0 {
+		t.Errorf("Update and Purge listed the tasks %d times, want none", store.lists)
+	}
+}
@@ -0,0 +1,663 @@
+package tasks
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unf6/testing/models"
+	"github.com/unf6/testing/pkg/database"
+	"github.com/unf6/testing/pkg/encryption"
+	"github.com/unf6/testing/pkg/utils"
+)
+
+// Statuses are the valid task statuses.
+var Statuses = []string{"pending", "in-progress", "completed"}
+
+var csvHeaders = []string{"ID", "TITLE", "DESCRIPTION", "STATUS", "CREATED AT", "UPDATED AT", "UID", "DELETED AT", "ARCHIVED AT", "PROJECT", "TAGS"}
+
+// taskColumns lists the tasks table columns in the order scanTask reads them.
+const taskColumns = "id, uid, title, description, status, created_at, updated_at, deleted_at, archived_at, project, tags"
+
+// Store is a backend tasks can be read from and written to.
+type Store interface {
+	Name() string
+	// Key identifies the backend in the history, journal and time entries.
+	Key() string
+	// List returns the tasks that are neither in the trash nor archived.
+	List() ([]models.Task, error)
+	// Trashed returns the tasks that are in the trash.
+	Trashed() ([]models.Task, error)
+	// Archived returns the archived tasks that are not in the trash.
+	Archived() ([]models.Task, error)
+	// Add stores the task keeping its IDs and timestamps. When the ID or UID
+	// is already taken a new one is assigned; the stored task is returned.
+	Add(task models.Task) (models.Task, error)
+	// Update saves the task, moving it in or out of the trash and the
+	// archive following DeletedAt and ArchivedAt.
+	Update(task models.Task) error
+	// Delete removes the task for good.
+	Delete(id int) error
+}
+
+// UIDLookup is implemented by the stores reading tasks by UID without
+// reading every task, see Service.ByUID.
+type UIDLookup interface {
+	// ByUID returns the tasks with the UIDs, archived and trashed ones
+	// included.
+	ByUID(uids []string) ([]models.Task, error)
+}
+
+// IDLookup is implemented by the stores reading a task by integer ID without
+// reading every task, see Service.ByID.
+type IDLookup interface {
+	// ByID returns the task with the ID, archived and trashed ones
+	// included, or a NotFoundError.
+	ByID(id int) (models.Task, error)
+}
+
+// ChangeFunc is called after a store changed a task, with the key of the
+// store. before is nil for created tasks, after for deleted ones.
+type ChangeFunc func(backend string, before *models.Task, after *models.Task) error
+
+// NotFoundError is returned when no task matches a reference. It matches
+// ErrNotFound with errors.Is.
+type NotFoundError struct {
+	Ref string
+}
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("no task found with ID %s", e.Ref)
+}
+
+func (e NotFoundError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
+// Find looks a task that is not in the trash up by its integer ID or by a
+// unique prefix of its UID.
+func Find(store Store, ref string) (models.Task, error) {
+	tasks, err := store.List()
+	if err != nil {
+		return models.Task{}, err
+	}
+	return Match(tasks, ref)
+}
+
+// Match picks the task with the integer ID or unique UID prefix ref.
+// Integer IDs take precedence over UID prefixes made of digits only.
+func Match(tasks []models.Task, ref string) (models.Task, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, task := range tasks {
+			if task.ID == id {
+				return task, nil
+			}
+		}
+	}
+
+	if !utils.IsULIDPrefix(ref) {
+		return models.Task{}, NotFoundError{ref}
+	}
+
+	var matches []models.Task
+	for _, task := range tasks {
+		if strings.HasPrefix(task.UID, strings.ToUpper(ref)) {
+			matches = append(matches, task)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return models.Task{}, NotFoundError{ref}
+	case 1:
+		return matches[0], nil
+	}
+	return models.Task{}, AmbiguousError{Ref: ref, Matches: len(matches)}
+}
+
+// ParseCSVTime parses a timestamp as written by the CSV store, falling back to RFC3339.
+func ParseCSVTime(value string) (time.Time, error) {
+	parsed, err := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", value)
+	if err != nil {
+		return time.Parse(time.RFC3339, value)
+	}
+	return parsed, nil
+}
+
+// formatOptionalCSVTime formats a time like the CSV store does, or returns an empty string.
+func formatOptionalCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().String()
+}
+
+// splitTags splits the comma separated tags of a stored task.
+func splitTags(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// SQLiteStore keeps tasks in the tasks table of a database opened with
+// database.Open. OnChange, when set, is called after every change. Keys
+// returns the key of a database encrypted with Encrypt when it is first used.
+type SQLiteStore struct {
+	DB       *sql.DB
+	OnChange ChangeFunc
+	Keys     encryption.KeyFunc
+
+	mu       sync.Mutex
+	key      *encryption.Key
+	unlocked bool
+	// tx holds the transaction of a store passed by InTx.
+	tx *sql.Tx
+}
+
+// queryer is what the store queries the database with, *sql.DB or *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (s *SQLiteStore) conn() queryer {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.DB
+}
+
+// InTx calls fn with a store making its changes in one transaction,
+// committed when fn returns nil and rolled back otherwise. OnChange is called
+// within the transaction, it must not write to the database through another
+// connection.
+func (s *SQLiteStore) InTx(fn func(store *SQLiteStore) error) error {
+	key, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&SQLiteStore{DB: s.DB, OnChange: s.OnChange, Keys: s.Keys, key: key, unlocked: true, tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	return nil
+}
+
+// OpenSQLite opens the database at path, creating it when missing.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	db, err := database.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteStore{DB: db}, nil
+}
+
+func (s *SQLiteStore) Name() string {
+	return "Database (sqlite)"
+}
+
+func (s *SQLiteStore) Key() string {
+	return "sqlite"
+}
+
+func (s *SQLiteStore) List() ([]models.Task, error) {
+	return s.query("SELECT " + taskColumns + " FROM tasks WHERE deleted_at IS NULL AND archived_at IS NULL ORDER BY id")
+}
+
+func (s *SQLiteStore) Trashed() ([]models.Task, error) {
+	return s.query("SELECT " + taskColumns + " FROM tasks WHERE deleted_at IS NOT NULL ORDER BY id")
+}
+
+func (s *SQLiteStore) Archived() ([]models.Task, error) {
+	return s.query("SELECT " + taskColumns + " FROM tasks WHERE deleted_at IS NULL AND archived_at IS NOT NULL ORDER BY id")
+}
+
+func (s *SQLiteStore) ByUID(uids []string) ([]models.Task, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	args := make([]interface{}, len(uids))
+	for i, uid := range uids {
+		args[i] = uid
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(uids)), ", ")
+	return s.query("SELECT "+taskColumns+" FROM tasks WHERE uid IN ("+placeholders+") ORDER BY id", args...)
+}
+
+func (s *SQLiteStore) ByID(id int) (models.Task, error) {
+	tasks, err := s.query("SELECT "+taskColumns+" FROM tasks WHERE id = ?", id)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if len(tasks) == 0 {
+		return models.Task{}, NotFoundError{strconv.Itoa(id)}
+	}
+	return tasks[0], nil
+}
+
+func (s *SQLiteStore) query(query string, args ...interface{}) ([]models.Task, error) {
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying SQLite database: %v", err)
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning SQLite row: %v", err)
+		}
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		if err := s.decrypt(&tasks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tasks, nil
+}
+
+// scanTask reads a task selected with taskColumns.
+func scanTask(row interface{ Scan(...interface{}) error }) (models.Task, error) {
+	var task models.Task
+	var description, project, tags sql.NullString
+	var deletedAt, archivedAt sql.NullTime
+	if err := row.Scan(&task.ID, &task.UID, &task.Title, &description, &task.Status, &task.CreatedAt, &task.UpdatedAt, &deletedAt, &archivedAt, &project, &tags); err != nil {
+		return task, err
+	}
+	task.Description = description.String
+	task.Project = project.String
+	task.Tags = splitTags(tags.String)
+	if deletedAt.Valid {
+		task.DeletedAt = &deletedAt.Time
+	}
+	if archivedAt.Valid {
+		task.ArchivedAt = &archivedAt.Time
+	}
+	return task, nil
+}
+
+// nullableTime converts an optional time into a value the driver can store.
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (s *SQLiteStore) Add(task models.Task) (models.Task, error) {
+	var exists, uidExists bool
+	if err := s.conn().QueryRow(`SELECT EXISTS(SELECT 1 FROM tasks WHERE id = ?)`, task.ID).Scan(&exists); err != nil {
+		return task, fmt.Errorf("error checking if task exists: %v", err)
+	}
+	if err := s.conn().QueryRow(`SELECT EXISTS(SELECT 1 FROM tasks WHERE uid = ?)`, task.UID).Scan(&uidExists); err != nil {
+		return task, fmt.Errorf("error checking if task exists: %v", err)
+	}
+
+	var id interface{}
+	if task.ID > 0 && !exists {
+		id = task.ID
+	}
+	if task.UID == "" || uidExists {
+		task.UID = utils.NewULID(task.CreatedAt)
+	}
+
+	key, err := s.cipher()
+	if err != nil {
+		return task, err
+	}
+	result, err := s.conn().Exec(`INSERT INTO tasks (id, uid, title, description, status, created_at, updated_at, deleted_at, archived_at, project, tags) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, task.UID, key.EncryptString(task.Title, taskLocation("title", task.UID)), key.EncryptString(task.Description, taskLocation("description", task.UID)),
+		task.Status, task.CreatedAt.UTC(), task.UpdatedAt.UTC(), nullableTime(task.DeletedAt), nullableTime(task.ArchivedAt),
+		key.EncryptString(task.Project, taskLocation("project", task.UID)), key.EncryptString(strings.Join(task.Tags, ","), taskLocation("tags", task.UID)))
+	if err != nil {
+		return task, fmt.Errorf("error inserting task into database: %v", err)
+	}
+
+	newID, err := result.LastInsertId()
+	if err != nil {
+		return task, fmt.Errorf("error reading inserted task id: %v", err)
+	}
+	task.ID = int(newID)
+	return task, s.changed(nil, &task)
+}
+
+func (s *SQLiteStore) get(id int) (models.Task, error) {
+	task, err := scanTask(s.conn().QueryRow("SELECT "+taskColumns+" FROM tasks WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return task, fmt.Errorf("task with ID %d not found", id)
+	}
+	if err != nil {
+		return task, fmt.Errorf("error querying SQLite database: %v", err)
+	}
+	return task, s.decrypt(&task)
+}
+
+func (s *SQLiteStore) Update(task models.Task) error {
+	old, err := s.get(task.ID)
+	if err != nil {
+		return err
+	}
+	key, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	updateQuery := `
+		UPDATE tasks
+		SET title = ?, description = ?, status = ?, updated_at = ?, deleted_at = ?, archived_at = ?, project = ?, tags = ?
+		WHERE id = ?
+	`
+	// The UID of a task never changes, the encrypted values stay bound to it.
+	if _, err := s.conn().Exec(updateQuery, key.EncryptString(task.Title, taskLocation("title", old.UID)), key.EncryptString(task.Description, taskLocation("description", old.UID)),
+		task.Status, task.UpdatedAt.UTC(), nullableTime(task.DeletedAt), nullableTime(task.ArchivedAt),
+		key.EncryptString(task.Project, taskLocation("project", old.UID)), key.EncryptString(strings.Join(task.Tags, ","), taskLocation("tags", old.UID)), task.ID); err != nil {
+		return fmt.Errorf("failed to update the task: %v", err)
+	}
+	return s.changed(&old, &task)
+}
+
+func (s *SQLiteStore) Delete(id int) error {
+	old, err := s.get(id)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.conn().Exec(`DELETE FROM tasks WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("error deleting task %d: %v", id, err)
+	}
+	return s.changed(&old, nil)
+}
+
+func (s *SQLiteStore) changed(before *models.Task, after *models.Task) error {
+	if s.OnChange == nil {
+		return nil
+	}
+	return s.OnChange(s.Key(), before, after)
+}
+
+// CSVStore keeps tasks in the CSV file at Path, which is created by the
+// first change. OnChange, when set, is called after every change. Keys returns
+// the key of a file encrypted with Encrypt, which stays encrypted when it is
+// written back.
+type CSVStore struct {
+	Path     string
+	OnChange ChangeFunc
+	Keys     encryption.KeyFunc
+
+	key *encryption.Key
+}
+
+func (s *CSVStore) Name() string {
+	return "CSV File"
+}
+
+func (s *CSVStore) Key() string {
+	return "csv"
+}
+
+func (s *CSVStore) List() ([]models.Task, error) {
+	return s.filter(func(task models.Task) bool {
+		return task.DeletedAt == nil && task.ArchivedAt == nil
+	})
+}
+
+func (s *CSVStore) Trashed() ([]models.Task, error) {
+	return s.filter(func(task models.Task) bool {
+		return task.DeletedAt != nil
+	})
+}
+
+func (s *CSVStore) Archived() ([]models.Task, error) {
+	return s.filter(func(task models.Task) bool {
+		return task.DeletedAt == nil && task.ArchivedAt != nil
+	})
+}
+
+// filter returns the tasks of the file for which keep returns true.
+func (s *CSVStore) filter(keep func(models.Task) bool) ([]models.Task, error) {
+	tasks, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if keep(task) {
+			kept = append(kept, task)
+		}
+	}
+	return kept, nil
+}
+
+// readAll returns every task of the file, trashed and archived ones included,
+// none when it is missing. The file is left as is.
+func (s *CSVStore) readAll() ([]models.Task, error) {
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV file: %v", err)
+	}
+	if encryption.IsEncrypted(data) {
+		if data, s.key, err = encryption.Decrypt(data, s.keys); err != nil {
+			return nil, fmt.Errorf("error decrypting CSV file: %w", err)
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV file: %v", err)
+	}
+
+	var tasks []models.Task
+	for i, record := range records {
+		if i == 0 || len(record) < 6 {
+			// Skip header row
+			continue
+		}
+
+		id, err := strconv.Atoi(record[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the ID %q: %v", record[0], err)
+		}
+		createdAt, err := ParseCSVTime(record[4])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing created_at of task %d: %v", id, err)
+		}
+		updatedAt, err := ParseCSVTime(record[5])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing updated_at of task %d: %v", id, err)
+		}
+
+		// Tasks written before tasks had a UID get one derived from their
+		// record, the same on every read until the next write stores it.
+		var uid string
+		if len(record) > 6 && record[6] != "" {
+			uid = record[6]
+		} else {
+			uid = utils.DerivedULID(createdAt, strings.Join(record[:6], "\x00"))
+		}
+
+		task := models.Task{
+			ID:          id,
+			UID:         uid,
+			Title:       record[1],
+			Description: record[2],
+			Status:      record[3],
+			CreatedAt:   createdAt,
+			UpdatedAt:   updatedAt,
+		}
+		if len(record) > 7 && record[7] != "" {
+			deletedAt, err := ParseCSVTime(record[7])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing deleted_at of task %d: %v", id, err)
+			}
+			task.DeletedAt = &deletedAt
+		}
+		if len(record) > 8 && record[8] != "" {
+			archivedAt, err := ParseCSVTime(record[8])
+			if err != nil {
+				return nil, fmt.Errorf("error parsing archived_at of task %d: %v", id, err)
+			}
+			task.ArchivedAt = &archivedAt
+		}
+		if len(record) > 10 {
+			task.Project = record[9]
+			task.Tags = splitTags(record[10])
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *CSVStore) Add(task models.Task) (models.Task, error) {
+	tasks, err := s.readAll()
+	if err != nil {
+		return task, err
+	}
+
+	maxID, taken, uidTaken := 0, false, false
+	for _, existing := range tasks {
+		if existing.ID > maxID {
+			maxID = existing.ID
+		}
+		if existing.ID == task.ID {
+			taken = true
+		}
+		if existing.UID == task.UID {
+			uidTaken = true
+		}
+	}
+	if task.ID <= 0 || taken {
+		task.ID = maxID + 1
+	}
+	if task.UID == "" || uidTaken {
+		task.UID = utils.NewULID(task.CreatedAt)
+	}
+
+	tasks = append(tasks, task)
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	if err := s.write(tasks); err != nil {
+		return task, err
+	}
+	return task, s.changed(nil, &task)
+}
+
+func (s *CSVStore) Update(task models.Task) error {
+	tasks, err := s.readAll()
+	if err != nil {
+		return err
+	}
+
+	for i, existing := range tasks {
+		if existing.ID == task.ID {
+			task.UID = existing.UID
+			tasks[i] = task
+			if err := s.write(tasks); err != nil {
+				return err
+			}
+			return s.changed(&existing, &task)
+		}
+	}
+	return fmt.Errorf("task with ID %d not found in CSV file", task.ID)
+}
+
+func (s *CSVStore) Delete(id int) error {
+	tasks, err := s.readAll()
+	if err != nil {
+		return err
+	}
+
+	var deleted *models.Task
+	kept := make([]models.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if task.ID == id {
+			deleted = &task
+			continue
+		}
+		kept = append(kept, task)
+	}
+	if deleted == nil {
+		return fmt.Errorf("task with ID %d not found in CSV file", id)
+	}
+	if err := s.write(kept); err != nil {
+		return err
+	}
+	return s.changed(deleted, nil)
+}
+
+// write replaces the content of the CSV file with the given tasks.
+func (s *CSVStore) write(tasks []models.Task) error {
+	content, err := encodeCSV(tasks, s.key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.Path, content, 0644); err != nil {
+		return fmt.Errorf("error writing to CSV: %v", err)
+	}
+	return nil
+}
+
+// encodeCSV returns the content of a CSV file holding the given tasks,
+// encrypted with key unless it is nil.
+func encodeCSV(tasks []models.Task, key *encryption.Key) ([]byte, error) {
+	records := [][]string{csvHeaders}
+	for _, task := range tasks {
+		records = append(records, []string{
+			strconv.Itoa(task.ID),
+			task.Title,
+			task.Description,
+			task.Status,
+			task.CreatedAt.UTC().String(),
+			task.UpdatedAt.UTC().String(),
+			task.UID,
+			formatOptionalCSVTime(task.DeletedAt),
+			formatOptionalCSVTime(task.ArchivedAt),
+			task.Project,
+			strings.Join(task.Tags, ","),
+		})
+	}
+
+	var data bytes.Buffer
+	writer := csv.NewWriter(&data)
+	if err := writer.WriteAll(records); err != nil {
+		return nil, fmt.Errorf("error writing to CSV: %v", err)
+	}
+
+	if key != nil {
+		return key.Encrypt(data.Bytes()), nil
+	}
+	return data.Bytes(), nil
+}
+
+func (s *CSVStore) changed(before *models.Task, after *models.Task) error {
+	if s.OnChange == nil {
+		return nil
+	}
+	return s.OnChange(s.Key(), before, after)
+}
@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCSVStoreReadsWithoutWriting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.csv")
+	store := &CSVStore{Path: path}
+
+	if tasks, err := store.List(); err != nil || len(tasks) != 0 {
+		t.Fatalf("List of a missing file = %v, %v", tasks, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("List created the file: %v", err)
+	}
+
+	// A file written before tasks had a UID.
+	legacy := []byte("ID,TITLE,DESCRIPTION,STATUS,CREATED AT,UPDATED AT\n" +
+		"1,Call Acme,,pending,2024-01-01 09:00:00 +0000 UTC,2024-01-01 09:00:00 +0000 UTC\n" +
+		"2,Call Globex,,completed,2024-01-01 09:00:00 +0000 UTC,2024-01-02 09:00:00 +0000 UTC\n")
+	if err := os.WriteFile(path, legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	second, _ := (&CSVStore{Path: path}).List()
+	if len(first) != 2 || first[0].UID == "" || first[0].UID == first[1].UID {
+		t.Fatalf("tasks = %+v, want two tasks with their own UID", first)
+	}
+	for i := range first {
+		if first[i].UID != second[i].UID {
+			t.Errorf("task %d read with UID %s then %s", first[i].ID, first[i].UID, second[i].UID)
+		}
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, legacy) {
+		t.Fatalf("List rewrote the file:\n%s", data)
+	}
+
+	// The next change stores the UIDs the tasks were read with.
+	first[1].Status = "pending"
+	if err := store.Update(first[1]); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	stored, _ := (&CSVStore{Path: path}).List()
+	if len(stored) != 2 || stored[0].UID != first[0].UID || stored[1].UID != first[1].UID {
+		t.Errorf("stored tasks = %+v, want the UIDs %s and %s", stored, first[0].UID, first[1].UID)
+	}
+	if data, _ := os.ReadFile(path); !bytes.Contains(data, []byte(first[0].UID)) {
+		t.Errorf("the UIDs were not written:\n%s", data)
+	}
+}
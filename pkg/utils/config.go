@@ -1,31 +1,112 @@
 package utils
 
 import (
-    "fmt"
-    "os"
-    "path/filepath"
-    "runtime"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 )
 
+// GetConfigDir returns the directory of the config file, creating it if
+// needed: $XDG_CONFIG_HOME/tasks-cli, %APPDATA%\tasks-cli on Windows or
+// ~/.config/tasks-cli.
 func GetConfigDir() string {
-    var configDir string
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return ensureDir(filepath.Join(dir, "tasks-cli"))
+	}
+	return ensureDir(legacyDir())
+}
 
-    switch runtime.GOOS{
-    case "windows":
-        configDir = filepath.Join(os.Getenv("APPDATA"), "tasks-cli")
-         default: 
-         configDir = filepath.Join(os.Getenv("HOME"), ".config", "tasks-cli")
-    }
+// GetDataDir returns the directory of the databases and CSV files, creating
+// it if needed: $XDG_DATA_HOME/tasks-cli, %LOCALAPPDATA%\tasks-cli on Windows
+// or ~/.local/share/tasks-cli. Data created before it was used stays where
+// it is: the directory of the old releases is returned while it holds a
+// database or CSV file and the data directory does not.
+func GetDataDir() string {
+	var dataDir string
+	switch {
+	case os.Getenv("XDG_DATA_HOME") != "":
+		dataDir = filepath.Join(os.Getenv("XDG_DATA_HOME"), "tasks-cli")
+	case runtime.GOOS == "windows":
+		dataDir = filepath.Join(os.Getenv("LOCALAPPDATA"), "tasks-cli")
+	default:
+		dataDir = filepath.Join(homeDir(), ".local", "share", "tasks-cli")
+	}
 
-    if _, err := os.Stat(configDir); os.IsNotExist(err) {
-		err := os.MkdirAll(configDir, 0755)
-		if err != nil {
-			fmt.Printf("Failed to create config directory: %v\n", err)
-			os.Exit(1)
+	if !hasTaskFiles(dataDir) && hasTaskFiles(legacyDir()) {
+		return legacyDir()
+	}
+	return ensureDir(dataDir)
+}
+
+// GetRuntimeDir returns the directory of the files that only last a session,
+// only accessible by the user: $XDG_RUNTIME_DIR/tasks-cli, or a directory of
+// the temporary directory otherwise. A directory created by another user is
+// skipped for the next one, the cache directory coming last.
+func GetRuntimeDir() (string, error) {
+	var dirs []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, filepath.Join(runtimeDir, "tasks-cli"))
+	}
+	dirs = append(dirs, filepath.Join(os.TempDir(), fmt.Sprintf("tasks-cli-%d", os.Getuid())))
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		dirs = append(dirs, filepath.Join(cacheDir, "tasks-cli", "runtime"))
+	}
+
+	for _, dir := range dirs {
+		// The directory of the temporary directory may have been created by
+		// another user, or be a file or a link of theirs.
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			continue
+		}
+		info, err := os.Lstat(dir)
+		if err != nil || !info.IsDir() || !ownedByUser(info) {
+			continue
+		}
+		if info.Mode().Perm() != 0700 {
+			return "", fmt.Errorf("%s must only be accessible by its owner", dir)
 		}
+		return dir, nil
 	}
+	return "", fmt.Errorf("none of %s is a directory owned by the user", strings.Join(dirs, ", "))
+}
 
-	return configDir
+// legacyDir is the directory old releases kept both the config and the data in.
+func legacyDir() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "tasks-cli")
+	}
+	return filepath.Join(homeDir(), ".config", "tasks-cli")
 }
 
+func homeDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Printf("Failed to find the home directory: %v\n", err)
+		os.Exit(1)
+	}
+	return home
+}
+
+// hasTaskFiles reports whether dir holds the database or CSV file of the
+// default workspace.
+func hasTaskFiles(dir string) bool {
+	for _, name := range []string{"tasks.db", "tasks.csv"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
 
+func ensureDir(dir string) string {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			fmt.Printf("Failed to create config directory: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestGetDataDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the directories are under APPDATA and LOCALAPPDATA")
+	}
+
+	tests := []struct {
+		name string
+		// legacy and data are the task files in the directory of the old
+		// releases and in the data directory.
+		legacy, data []string
+		xdg          bool
+		want         string
+	}{
+		{"new install", nil, nil, false, ".local/share/tasks-cli"},
+		{"XDG_DATA_HOME", nil, nil, true, "xdg/tasks-cli"},
+		{"legacy database", []string{"tasks.db"}, nil, false, ".config/tasks-cli"},
+		{"legacy CSV file", []string{"tasks.csv"}, nil, true, ".config/tasks-cli"},
+		{"data directory in use", []string{"tasks.db"}, []string{"tasks.csv"}, false, ".local/share/tasks-cli"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("XDG_DATA_HOME", "")
+			dataDir := filepath.Join(home, ".local", "share", "tasks-cli")
+			if test.xdg {
+				t.Setenv("XDG_DATA_HOME", filepath.Join(home, "xdg"))
+				dataDir = filepath.Join(home, "xdg", "tasks-cli")
+			}
+			createFiles(t, filepath.Join(home, ".config", "tasks-cli"), test.legacy)
+			createFiles(t, dataDir, test.data)
+
+			want := filepath.Join(home, test.want)
+			if got := GetDataDir(); got != want {
+				t.Fatalf("GetDataDir() = %s, want %s", got, want)
+			}
+			if info, err := os.Stat(want); err != nil || !info.IsDir() {
+				t.Errorf("%s was not created: %v", want, err)
+			}
+		})
+	}
+}
+
+func TestGetConfigDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the directory is under APPDATA")
+	}
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if got, want := GetConfigDir(), filepath.Join(home, ".config", "tasks-cli"); got != want {
+		t.Errorf("GetConfigDir() = %s, want %s", got, want)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "xdg"))
+	if got, want := GetConfigDir(), filepath.Join(home, "xdg", "tasks-cli"); got != want {
+		t.Errorf("GetConfigDir() with XDG_CONFIG_HOME = %s, want %s", got, want)
+	}
+}
+
+func TestGetRuntimeDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are not checked on Windows")
+	}
+
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	dir, err := GetRuntimeDir()
+	if err != nil {
+		t.Fatalf("GetRuntimeDir: %v", err)
+	}
+	if want := filepath.Join(runtimeDir, "tasks-cli"); dir != want {
+		t.Errorf("GetRuntimeDir() = %s, want %s", dir, want)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	} else if info.Mode().Perm() != 0700 {
+		t.Errorf("%s has mode %v, want it only accessible by its owner", dir, info.Mode().Perm())
+	}
+
+	// A directory others can read is refused.
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetRuntimeDir(); err == nil {
+		t.Error("GetRuntimeDir accepted a directory readable by others")
+	}
+}
+
+func TestGetRuntimeDirFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("owners are not checked on Windows")
+	}
+
+	tests := []struct {
+		name string
+		// squat takes the path of the runtime directory before it is created.
+		squat func(t *testing.T, dir string)
+	}{
+		{name: "owned by another user", squat: func(t *testing.T, dir string) {
+			if os.Getuid() != 0 {
+				t.Skip("giving a directory to another user needs root")
+			}
+			if err := os.Mkdir(dir, 0700); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chown(dir, os.Getuid()+1000, -1); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "file", squat: func(t *testing.T, dir string) {
+			if err := os.WriteFile(dir, nil, 0600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "link", squat: func(t *testing.T, dir string) {
+			if err := os.Symlink(t.TempDir(), dir); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtimeDir, tempDir := t.TempDir(), t.TempDir()
+			t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+			t.Setenv("TMPDIR", tempDir)
+			tt.squat(t, filepath.Join(runtimeDir, "tasks-cli"))
+
+			dir, err := GetRuntimeDir()
+			if err != nil {
+				t.Fatalf("GetRuntimeDir: %v", err)
+			}
+			if want := filepath.Join(tempDir, fmt.Sprintf("tasks-cli-%d", os.Getuid())); dir != want {
+				t.Errorf("GetRuntimeDir() = %s, want the fallback %s", dir, want)
+			}
+		})
+	}
+}
+
+func createFiles(t *testing.T, dir string, names []string) {
+	t.Helper()
+	for _, name := range names {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package utils
+
+import "os"
+
+// ownedByUser reports whether the file described by info belongs to the
+// current user. Windows keeps the temporary directory of each user apart.
+func ownedByUser(info os.FileInfo) bool {
+	return true
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"strings"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID for the given time: 48 bits of milliseconds followed
+// by 80 random bits, encoded as 26 Crockford base32 characters so that IDs
+// sort by creation time.
+func NewULID(t time.Time) string {
+	var entropy [10]byte
+	if _, err := rand.Read(entropy[:]); err != nil {
+		panic(err)
+	}
+	return encodeULID(t, entropy[:])
+}
+
+// DerivedULID returns the ULID for the given time whose 80 bits following the
+// milliseconds are derived from seed, the same on every call.
+func DerivedULID(t time.Time, seed string) string {
+	sum := sha256.Sum256([]byte(seed))
+	return encodeULID(t, sum[:10])
+}
+
+// encodeULID encodes the milliseconds of t followed by 10 bytes of entropy.
+func encodeULID(t time.Time, entropy []byte) string {
+	var data [16]byte
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		data[i] = byte(ms)
+		ms >>= 8
+	}
+	copy(data[6:], entropy)
+
+	// 128 bits are encoded as 130, the two leading bits are always zero.
+	var out [26]byte
+	var acc uint32
+	bits := 2
+	pos := 0
+	for _, b := range data {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = crockford[(acc>>uint(bits))&31]
+			pos++
+		}
+	}
+	return string(out[:])
+}
+
+// IsULIDPrefix reports whether s only contains characters a ULID can hold.
+func IsULIDPrefix(s string) bool {
+	if s == "" || len(s) > 26 {
+		return false
+	}
+	for _, c := range strings.ToUpper(s) {
+		if !strings.ContainsRune(crockford, c) {
+			return false
+		}
+	}
+	return true
+}